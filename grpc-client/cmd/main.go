@@ -4,36 +4,88 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	pb "github.com/ParasJain0307/grpc-project/grpc-client/api" // Update with your actual package path
+	"github.com/ParasJain0307/grpc-project/grpc-client/internal/config"
+	"github.com/ParasJain0307/grpc-project/grpc-client/internal/tlsutil"
+	"github.com/ParasJain0307/grpc-project/grpc-client/internal/tracing"
 	"github.com/ParasJain0307/grpc-project/grpc-client/internal/utils/logger"
 	"github.com/ParasJain0307/grpc-project/grpc-client/internal/validation"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
-)
-
-const (
-	serverAddress = "localhost:50052" // Address of the gRPC server
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 var loggerv1 *zap.SugaredLogger
 
 func main() {
+	// Load the configuration from the config file, environment variables and flags
+	cfg, printOnly, err := config.Load(os.Args[0], os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if printOnly {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatalf("Failed to print configuration: %v", err)
+		}
+		return
+	}
+
 	// Initialize the logger
-	var err error
 	loggerv1, err = logger.InitLogger()
 	if err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
 	}
 	defer loggerv1.Sync() // Ensure any buffered log entries are flushed before the program exits
 
-	// Set up a connection to the server
-	conn, err := grpc.Dial(serverAddress, grpc.WithInsecure())
+	// Export a span for every RPC, propagated to the server as W3C trace context
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Options())
+	if err != nil {
+		loggerv1.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			loggerv1.Errorf("Failed to flush traces: %v", err)
+		}
+	}()
+
+	// Set up a connection to the server, over TLS when configured
+	creds := insecure.NewCredentials()
+	if cfg.Server.TLS.Enabled {
+		tlsConfig, err := tlsutil.NewClientConfig(cfg.Server.Address, cfg.Server.TLS.Options())
+		if err != nil {
+			loggerv1.Fatalf("Failed to load TLS certificates: %v", err)
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	}
+	if authorization := cfg.Auth.Authorization(); authorization != "" {
+		if !cfg.Server.TLS.Enabled {
+			loggerv1.Warn("Sending credentials without TLS")
+		}
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(authorizationCredentials(authorization)))
+	}
+	conn, err := grpc.Dial(cfg.Server.Address, dialOpts...)
 	if err != nil {
 		loggerv1.Fatalf("Failed to dial server: %v", err)
 	}
@@ -118,6 +170,63 @@ func main() {
 			}
 			searchUsers(client, criterias)
 
+		case "7":
+			// Example: Stream users matching criteria
+			loggerv1.Info("Enter search criteria:")
+			criterias := readSearchCriteria(reader)
+			if err := validation.ValidateSearchCriteria(criterias); err != nil {
+				loggerv1.Errorf("Validation error: %v", err)
+				break
+			}
+			streamSearchUsers(client, criterias)
+
+		case "4":
+			// Example: Create a user
+			loggerv1.Info("Enter user fields:")
+			user, _, err := readUserFields(reader)
+			if err != nil {
+				loggerv1.Errorf("Validation error: %v", err)
+				break
+			}
+			createUser(client, user)
+
+		case "5":
+			// Example: Update a user
+			loggerv1.Info("Enter user ID: ")
+			userIDInput, err := reader.ReadString('\n')
+			if err != nil {
+				loggerv1.Errorf("Error reading user ID input: %v", err)
+				break
+			}
+			if err := validation.ValidateUserID(userIDInput); err != nil {
+				loggerv1.Errorf("Validation error: %v", err)
+				break
+			}
+			userID, _ := strconv.Atoi(strings.TrimSpace(userIDInput))
+			loggerv1.Info("Enter fields to update:")
+			user, paths, err := readUserFields(reader)
+			if err != nil {
+				loggerv1.Errorf("Validation error: %v", err)
+				break
+			}
+			user.Id = int32(userID)
+			updateUser(client, user, paths)
+
+		case "6":
+			// Example: Delete a user
+			loggerv1.Info("Enter user ID: ")
+			userIDInput, err := reader.ReadString('\n')
+			if err != nil {
+				loggerv1.Errorf("Error reading user ID input: %v", err)
+				break
+			}
+			if err := validation.ValidateUserID(userIDInput); err != nil {
+				loggerv1.Errorf("Validation error: %v", err)
+				break
+			}
+			userID, _ := strconv.Atoi(strings.TrimSpace(userIDInput))
+			deleteUser(client, int32(userID))
+
 		case "q":
 			// Quit
 			loggerv1.Info("Exiting...")
@@ -144,8 +253,28 @@ func readSearchCriteria(reader *bufio.Reader) []*pb.SearchCriteria {
 			break
 		}
 
+		// Prompt user for the comparison operator, equality by default
+		fmt.Print("Operator (eq, ne, lt, lte, gt, gte, in, prefix, contains, ieq, regex) [eq]: ")
+		operatorInput, err := reader.ReadString('\n')
+		if err != nil {
+			loggerv1.Fatalf("Error reading operator input: %v", err)
+		}
+		operatorInput = strings.ToUpper(strings.TrimSpace(operatorInput))
+		if operatorInput == "" {
+			operatorInput = pb.Operator_EQ.String()
+		}
+		operator, ok := pb.Operator_value[operatorInput]
+		if !ok {
+			loggerv1.Warnf("Unknown operator %q, skipping criteria", operatorInput)
+			continue
+		}
+
 		// Prompt user for criteria value
-		fmt.Print("Enter value for " + fieldNameInput + ": ")
+		if pb.Operator(operator) == pb.Operator_IN {
+			fmt.Print("Enter values for " + fieldNameInput + " (comma-separated): ")
+		} else {
+			fmt.Print("Enter value for " + fieldNameInput + ": ")
+		}
 		valueInput, err := reader.ReadString('\n')
 		if err != nil {
 			loggerv1.Fatalf("Error reading value input: %v", err)
@@ -154,8 +283,13 @@ func readSearchCriteria(reader *bufio.Reader) []*pb.SearchCriteria {
 
 		// Create SearchCriteria object and add to slice
 		criteria := &pb.SearchCriteria{
-			FieldName:  fieldNameInput,
-			FieldValue: valueInput,
+			FieldName: fieldNameInput,
+			Operator:  pb.Operator(operator),
+		}
+		if criteria.Operator == pb.Operator_IN {
+			criteria.FieldValues = strings.Split(valueInput, ",")
+		} else {
+			criteria.FieldValue = valueInput
 		}
 		criterias = append(criterias, criteria)
 	}
@@ -163,17 +297,133 @@ func readSearchCriteria(reader *bufio.Reader) []*pb.SearchCriteria {
 	return criterias
 }
 
+// readUserFields prompts for field name/value pairs and returns the resulting
+// user together with the names of the fields that were set.
+func readUserFields(reader *bufio.Reader) (*pb.User, []string, error) {
+	var criterias []*pb.SearchCriteria
+	for {
+		fmt.Print("Field Name (e.g., fname, city, phone, height, married; leave empty to finish): ")
+		fieldNameInput, err := reader.ReadString('\n')
+		if err != nil {
+			loggerv1.Fatalf("Error reading field name input: %v", err)
+		}
+		fieldNameInput = strings.TrimSpace(fieldNameInput)
+		if fieldNameInput == "" {
+			break
+		}
+
+		fmt.Print("Enter value for " + fieldNameInput + ": ")
+		valueInput, err := reader.ReadString('\n')
+		if err != nil {
+			loggerv1.Fatalf("Error reading value input: %v", err)
+		}
+		criterias = append(criterias, &pb.SearchCriteria{
+			FieldName:  fieldNameInput,
+			FieldValue: strings.TrimSpace(valueInput),
+		})
+	}
+
+	// The search criteria rules double as field validation
+	if err := validation.ValidateSearchCriteria(criterias); err != nil {
+		return nil, nil, err
+	}
+
+	user := &pb.User{}
+	var paths []string
+	for _, criteria := range criterias {
+		switch criteria.FieldName {
+		case "fname":
+			user.Fname = criteria.FieldValue
+		case "city":
+			user.City = criteria.FieldValue
+		case "phone":
+			user.Phone, _ = strconv.ParseInt(criteria.FieldValue, 10, 64)
+		case "height":
+			height, _ := strconv.ParseFloat(criteria.FieldValue, 32)
+			user.Height = float32(height)
+		case "married":
+			user.Married, _ = strconv.ParseBool(criteria.FieldValue)
+		}
+		paths = append(paths, criteria.FieldName)
+	}
+	return user, paths, nil
+}
+
+func createUser(client pb.UserServiceClient, user *pb.User) {
+	// Call the CreateUser RPC method
+	resp, err := client.CreateUser(context.Background(), &pb.CreateUserRequest{User: user})
+	if err != nil {
+		loggerv1.Errorf("Error creating user: %v", err)
+		return
+	}
+	loggerv1.Info(formatUserResponse(resp))
+}
+
+func updateUser(client pb.UserServiceClient, user *pb.User, paths []string) {
+	// Call the UpdateUser RPC method, only touching the fields that were entered
+	req := &pb.UpdateUserRequest{
+		User:       user,
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: paths},
+	}
+	resp, err := client.UpdateUser(context.Background(), req)
+	if err != nil {
+		loggerv1.Errorf("Error updating user: %v", err)
+		return
+	}
+	loggerv1.Info(formatUserResponse(resp))
+}
+
+func deleteUser(client pb.UserServiceClient, userID int32) {
+	// Call the DeleteUser RPC method
+	if _, err := client.DeleteUser(context.Background(), &pb.DeleteUserRequest{UserId: userID}); err != nil {
+		loggerv1.Errorf("Error deleting user: %v", err)
+		return
+	}
+	loggerv1.Infof("User %d deleted", userID)
+}
+
 func searchUsers(client pb.UserServiceClient, criterias []*pb.SearchCriteria) {
 	// Call the SearchUsers RPC method with multiple criterias
 	req := &pb.SearchUsersRequest{
 		Criterias: criterias,
 	}
-	resp, err := client.SearchUsers(context.Background(), req)
+	// Follow next_page_token until every matching user has been printed
+	for {
+		resp, err := client.SearchUsers(context.Background(), req)
+		if err != nil {
+			loggerv1.Errorf("Error searching users: %v", err)
+			return
+		}
+		loggerv1.Info(formatUsersListResponse(resp))
+		if resp.NextPageToken == "" {
+			loggerv1.Infof("Found %d users", resp.TotalSize)
+			return
+		}
+		req.PageToken = resp.NextPageToken
+	}
+}
+
+func streamSearchUsers(client pb.UserServiceClient, criterias []*pb.SearchCriteria) {
+	// Call the StreamSearchUsers RPC method and print users as they arrive
+	stream, err := client.StreamSearchUsers(context.Background(), &pb.SearchUsersRequest{Criterias: criterias})
 	if err != nil {
-		loggerv1.Errorf("Error searching users: %v", err)
+		loggerv1.Errorf("Error streaming users: %v", err)
 		return
 	}
-	loggerv1.Info(formatUsersListResponse(resp))
+	count := 0
+	for {
+		user, err := stream.Recv()
+		if err == io.EOF {
+			loggerv1.Infof("Streamed %d users", count)
+			return
+		}
+		if err != nil {
+			loggerv1.Errorf("Error streaming users: %v", err)
+			return
+		}
+		count++
+		loggerv1.Info(formatUserResponse(user))
+	}
 }
 
 func printMenu() {
@@ -181,6 +431,10 @@ func printMenu() {
 	fmt.Println("1. Fetch User by ID")
 	fmt.Println("2. Fetch Users by IDs")
 	fmt.Println("3. Search Users by Criteria")
+	fmt.Println("4. Create User")
+	fmt.Println("5. Update User")
+	fmt.Println("6. Delete User")
+	fmt.Println("7. Stream Search Users")
 	fmt.Println("q. Quit")
 	fmt.Print("Enter your choice: ")
 }
@@ -197,12 +451,20 @@ func getUserByID(client pb.UserServiceClient, userID int32) {
 
 func getUsersByID(client pb.UserServiceClient, userIDs []int32) {
 	// Call the GetUsersByID RPC method
-	resp, err := client.GetUsersByID(context.Background(), &pb.GetUsersByIDRequest{UserIds: userIDs})
-	if err != nil {
-		loggerv1.Errorf("Error fetching users by IDs: %v", err)
-		return
+	req := &pb.GetUsersByIDRequest{UserIds: userIDs}
+	// Follow next_page_token until every requested user has been printed
+	for {
+		resp, err := client.GetUsersByID(context.Background(), req)
+		if err != nil {
+			loggerv1.Errorf("Error fetching users by IDs: %v", err)
+			return
+		}
+		loggerv1.Info(formatUsersListResponse(resp))
+		if resp.NextPageToken == "" {
+			return
+		}
+		req.PageToken = resp.NextPageToken
 	}
-	loggerv1.Info(formatUsersListResponse(resp))
 }
 
 func formatUserResponse(user *pb.User) string {
@@ -231,3 +493,15 @@ func formatUsersListResponse(users *pb.UsersList) string {
 	}
 	return builder.String()
 }
+
+// authorizationCredentials sends a fixed authorization metadata value with every RPC
+type authorizationCredentials string
+
+func (a authorizationCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": string(a)}, nil
+}
+
+// RequireTransportSecurity is false so credentials can be used against a local plaintext server
+func (a authorizationCredentials) RequireTransportSecurity() bool {
+	return false
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/ParasJain0307/grpc-project/grpc-client/internal/tlsutil"
+	"github.com/ParasJain0307/grpc-project/grpc-client/internal/tracing"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the configuration of the gRPC client
+type Config struct {
+	Server ServerConfig `yaml:"server" toml:"server"`
+	Auth   AuthConfig   `yaml:"auth" toml:"auth"`
+
+	Tracing TracingConfig `yaml:"tracing" toml:"tracing"`
+}
+
+// ServerConfig configures the connection to the gRPC server
+type ServerConfig struct {
+	Address string    `yaml:"address" toml:"address"` // host:port of the gRPC server
+	TLS     TLSConfig `yaml:"tls" toml:"tls"`
+}
+
+// TLSConfig configures TLS of the connection to the gRPC server
+type TLSConfig struct {
+	Enabled    bool   `yaml:"enabled" toml:"enabled"`
+	CAFile     string `yaml:"ca_file" toml:"ca_file"`         // CA bundle verifying the server, the system roots when empty
+	CertFile   string `yaml:"cert_file" toml:"cert_file"`     // Client certificate for mutual TLS
+	KeyFile    string `yaml:"key_file" toml:"key_file"`       // PEM private key of CertFile
+	ServerName string `yaml:"server_name" toml:"server_name"` // Name expected in the server certificate, the dialed host when empty
+}
+
+// Options returns the tlsutil options of the config
+func (c TLSConfig) Options() tlsutil.ClientOptions {
+	return tlsutil.ClientOptions{CAFile: c.CAFile, CertFile: c.CertFile, KeyFile: c.KeyFile, ServerName: c.ServerName}
+}
+
+// AuthConfig holds the credentials sent with every RPC, at most one of them may be set
+type AuthConfig struct {
+	APIKey string `yaml:"api_key" toml:"api_key"` // Sent as "authorization: ApiKey <key>"
+	Token  string `yaml:"token" toml:"token"`     // JWT sent as "authorization: Bearer <token>"
+}
+
+// Authorization returns the authorization metadata value, empty when no credentials are set
+func (c AuthConfig) Authorization() string {
+	switch {
+	case c.Token != "":
+		return "Bearer " + c.Token
+	case c.APIKey != "":
+		return "ApiKey " + c.APIKey
+	default:
+		return ""
+	}
+}
+
+// TracingConfig selects where OpenTelemetry spans are exported
+type TracingConfig struct {
+	ServiceName  string  `yaml:"service_name" toml:"service_name"`   // service.name of the exported spans
+	Exporter     string  `yaml:"exporter" toml:"exporter"`           // none, otlp or stdout
+	OTLPEndpoint string  `yaml:"otlp_endpoint" toml:"otlp_endpoint"` // host:port of the OTLP/gRPC collector
+	OTLPInsecure bool    `yaml:"otlp_insecure" toml:"otlp_insecure"` // Connect to the collector without TLS
+	File         string  `yaml:"file" toml:"file"`                   // File the stdout exporter appends to, stdout when empty
+	SampleRatio  float64 `yaml:"sample_ratio" toml:"sample_ratio"`   // Fraction of new traces recorded
+}
+
+// Options returns the tracing options of the config
+func (c TracingConfig) Options() tracing.Options {
+	return tracing.Options{
+		ServiceName:  c.ServiceName,
+		Exporter:     c.Exporter,
+		OTLPEndpoint: c.OTLPEndpoint,
+		OTLPInsecure: c.OTLPInsecure,
+		File:         c.File,
+		SampleRatio:  c.SampleRatio,
+	}
+}
+
+// Default returns the configuration used when nothing is overridden
+func Default() *Config {
+	return &Config{
+		Server:  ServerConfig{Address: "localhost:50051"},
+		Tracing: TracingConfig{ServiceName: "grpc-client", Exporter: tracing.ExporterNone, SampleRatio: 1},
+	}
+}
+
+// Validate reports every invalid setting
+func (c *Config) Validate() error {
+	var problems []string
+	if _, _, err := net.SplitHostPort(c.Server.Address); err != nil {
+		problems = append(problems, fmt.Sprintf("server.address: must be host:port, got %q", c.Server.Address))
+	}
+	if (c.Server.TLS.CertFile == "") != (c.Server.TLS.KeyFile == "") {
+		problems = append(problems, "server.tls: cert_file and key_file must be set together")
+	}
+	if c.Auth.APIKey != "" && c.Auth.Token != "" {
+		problems = append(problems, "auth: api_key and token must not both be set")
+	}
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout:
+	default:
+		problems = append(problems, fmt.Sprintf("tracing.exporter: must be none, otlp or stdout, got %q", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems = append(problems, "tracing.sample_ratio: must be between 0 and 1")
+	}
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Print writes the configuration as YAML, in the layout of a config file
+func (c *Config) Print(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	return enc.Close()
+}
@@ -0,0 +1,193 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileEnv names the environment variable selecting the config file
+const ConfigFileEnv = "CONFIG_FILE"
+
+// setting binds a Config field to its environment variable and flag
+type setting struct {
+	key   string     // Dotted path of the field in the config file, e.g. "grpc.address"
+	env   string     // Environment variable overriding the file
+	usage string     // Flag help text
+	value flag.Value // Field the setting writes to
+}
+
+// flagName derives the flag of a setting from its key, e.g. "store.bolt_path" becomes "store-bolt-path"
+func (s setting) flagName() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(s.key)
+}
+
+// settings lists every setting of c that can be overridden
+func (c *Config) settings() []setting {
+	return []setting{
+		{"server.address", "GRPC_SERVER_ADDRESS", "address of the gRPC server", (*stringValue)(&c.Server.Address)},
+		{"server.tls.enabled", "GRPC_SERVER_TLS_ENABLED", "dial the gRPC server over TLS", (*boolValue)(&c.Server.TLS.Enabled)},
+		{"server.tls.ca_file", "GRPC_SERVER_TLS_CA_FILE", "CA bundle verifying the gRPC server, the system roots when empty", (*stringValue)(&c.Server.TLS.CAFile)},
+		{"server.tls.cert_file", "GRPC_SERVER_TLS_CERT_FILE", "client certificate presented for mutual TLS", (*stringValue)(&c.Server.TLS.CertFile)},
+		{"server.tls.key_file", "GRPC_SERVER_TLS_KEY_FILE", "PEM private key of the client certificate", (*stringValue)(&c.Server.TLS.KeyFile)},
+		{"server.tls.server_name", "GRPC_SERVER_TLS_SERVER_NAME", "name expected in the gRPC server certificate", (*stringValue)(&c.Server.TLS.ServerName)},
+		{"auth.api_key", "GRPC_API_KEY", "API key sent with every RPC", (*stringValue)(&c.Auth.APIKey)},
+		{"auth.token", "GRPC_BEARER_TOKEN", "JWT sent as bearer token with every RPC", (*stringValue)(&c.Auth.Token)},
+		{"tracing.service_name", "TRACING_SERVICE_NAME", "service.name of the exported spans", (*stringValue)(&c.Tracing.ServiceName)},
+		{"tracing.exporter", "TRACING_EXPORTER", "span exporter: none, otlp or stdout", (*stringValue)(&c.Tracing.Exporter)},
+		{"tracing.otlp_endpoint", "TRACING_OTLP_ENDPOINT", "host:port of the OTLP/gRPC collector (default from OTEL_EXPORTER_OTLP_ENDPOINT)", (*stringValue)(&c.Tracing.OTLPEndpoint)},
+		{"tracing.otlp_insecure", "TRACING_OTLP_INSECURE", "connect to the OTLP collector without TLS", (*boolValue)(&c.Tracing.OTLPInsecure)},
+		{"tracing.file", "TRACING_FILE", "file the stdout exporter appends spans to, stdout when empty", (*stringValue)(&c.Tracing.File)},
+		{"tracing.sample_ratio", "TRACING_SAMPLE_RATIO", "fraction of new traces recorded, between 0 and 1", (*floatValue)(&c.Tracing.SampleRatio)},
+	}
+}
+
+// Load builds the configuration from, in increasing order of precedence, the
+// defaults, the YAML or TOML file named by -config or CONFIG_FILE, environment
+// variables and the flags in args, then validates it. printOnly reports whether
+// -print-config was given, in which case the caller should print and exit.
+func Load(name string, args []string) (cfg *Config, printOnly bool, err error) {
+	cfg = Default()
+	settings := cfg.settings()
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv(ConfigFileEnv), "YAML or TOML config file (env "+ConfigFileEnv+")")
+	printConfig := fs.Bool("print-config", false, "print the effective configuration and exit")
+	// Flags are parsed into placeholders first, so they can be applied after the file and env
+	flags := make(map[string]*pendingFlag, len(settings))
+	byFlag := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		usage := fmt.Sprintf("%s (env %s, default %q)", s.usage, s.env, s.value.String())
+		_, boolean := s.value.(*boolValue)
+		flags[s.flagName()] = &pendingFlag{boolean: boolean}
+		fs.Var(flags[s.flagName()], s.flagName(), usage)
+		byFlag[s.flagName()] = s
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, false, err
+	}
+	if fs.NArg() > 0 {
+		return nil, false, fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, false, err
+		}
+	}
+	for _, s := range settings {
+		if v := os.Getenv(s.env); v != "" {
+			if err := s.value.Set(v); err != nil {
+				return nil, false, fmt.Errorf("invalid %s %q: %v", s.env, v, err)
+			}
+		}
+	}
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		s, ok := byFlag[f.Name]
+		if !ok || flagErr != nil {
+			return
+		}
+		if err := s.value.Set(flags[f.Name].value); err != nil {
+			flagErr = fmt.Errorf("invalid value %q for flag -%s: %v", flags[f.Name].value, f.Name, err)
+		}
+	})
+	if flagErr != nil {
+		return nil, false, flagErr
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, false, err
+	}
+	return cfg, *printConfig, nil
+}
+
+// loadFile overlays the settings of a YAML or TOML file, rejecting unknown keys
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %v", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("error parsing config file %s: %v", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), c)
+		if err != nil {
+			return fmt.Errorf("error parsing config file %s: %v", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("error parsing config file %s: unknown keys %v", path, undecoded)
+		}
+	default:
+		return fmt.Errorf("config file %s must have a .yaml, .yml or .toml extension", path)
+	}
+	return nil
+}
+
+// stringValue is a string field usable as a flag.Value
+type stringValue string
+
+func (s *stringValue) String() string { return string(*s) }
+
+func (s *stringValue) Set(v string) error {
+	*s = stringValue(v)
+	return nil
+}
+
+// boolValue is a bool field usable as a flag.Value
+type boolValue bool
+
+func (b *boolValue) String() string { return strconv.FormatBool(bool(*b)) }
+
+func (b *boolValue) Set(v string) error {
+	parsed, err := strconv.ParseBool(v)
+	if err != nil {
+		return err
+	}
+	*b = boolValue(parsed)
+	return nil
+}
+
+// floatValue is a float64 field usable as a flag.Value
+type floatValue float64
+
+func (f *floatValue) String() string { return strconv.FormatFloat(float64(*f), 'g', -1, 64) }
+
+func (f *floatValue) Set(v string) error {
+	parsed, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return err
+	}
+	*f = floatValue(parsed)
+	return nil
+}
+
+// pendingFlag holds a flag value until it is applied on top of the file and env
+type pendingFlag struct {
+	value   string
+	boolean bool
+}
+
+func (p *pendingFlag) String() string { return p.value }
+
+func (p *pendingFlag) Set(v string) error {
+	p.value = v
+	return nil
+}
+
+// IsBoolFlag lets boolean settings be given as a bare -flag
+func (p *pendingFlag) IsBoolFlag() bool { return p.boolean }
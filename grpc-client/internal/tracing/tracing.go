@@ -0,0 +1,93 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ParasJain0307/grpc-project/grpc-client/internal/utils/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Span exporters selectable in Options.Exporter
+const (
+	ExporterNone   = "none"   // Trace context is propagated but no spans are recorded
+	ExporterOTLP   = "otlp"   // Spans are sent to an OTLP/gRPC collector
+	ExporterStdout = "stdout" // Spans are written as JSON to stdout or a file, for local testing
+)
+
+// Options configures the tracer provider
+type Options struct {
+	ServiceName  string  // service.name resource attribute
+	Exporter     string  // One of the Exporter* constants
+	OTLPEndpoint string  // host:port of the collector, the OTEL_EXPORTER_OTLP_* env defaults when empty
+	OTLPInsecure bool    // Connect to the collector without TLS
+	File         string  // Output of the stdout exporter, stdout when empty
+	SampleRatio  float64 // Fraction of new traces recorded; sampled parents are always followed
+}
+
+// Setup installs the global tracer provider and the W3C trace context and baggage
+// propagators. The returned function flushes pending spans and must be called on exit.
+func Setup(ctx context.Context, opts Options) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var closeOutput func() error
+	switch opts.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		clientOpts := []otlptracegrpc.Option{}
+		if opts.OTLPEndpoint != "" {
+			clientOpts = append(clientOpts, otlptracegrpc.WithEndpoint(opts.OTLPEndpoint))
+		}
+		if opts.OTLPInsecure {
+			clientOpts = append(clientOpts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, clientOpts...)
+	case ExporterStdout:
+		var out io.Writer = os.Stdout
+		if opts.File != "" {
+			f, ferr := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+			if ferr != nil {
+				return nil, fmt.Errorf("error opening trace file: %v", ferr)
+			}
+			out, closeOutput = f, f.Close
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(out))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", opts.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error creating %s trace exporter: %v", opts.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(opts.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("error building trace resource: %v", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	logger.Infof("Tracing enabled with the %s exporter", opts.Exporter)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closeOutput != nil {
+			err = errors.Join(err, closeOutput())
+		}
+		return err
+	}, nil
+}
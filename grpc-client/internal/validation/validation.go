@@ -44,46 +44,75 @@ func ValidateSearchCriteria(criteria []*pb.SearchCriteria) error {
 			return fmt.Errorf("field name cannot be empty")
 		}
 
-		// Validate if field value is empty
-		if criteria.FieldValue == "" {
-			return fmt.Errorf("field value cannot be empty")
+		values := []string{criteria.FieldValue}
+		if criteria.Operator == pb.Operator_IN {
+			values = criteria.FieldValues
+			if len(values) == 0 {
+				return fmt.Errorf("IN requires at least one value")
+			}
 		}
 
-		// Additional custom validation rules based on field name (example rules)
-		switch criteria.FieldName {
-		case "fname":
-			// Example: Validate first name should be alphabetic
-			if !isValidAlphabetic(criteria.FieldValue) {
-				return fmt.Errorf("invalid first name format: %s", criteria.FieldValue)
-			}
-		case "city":
-			// Example: Validate city should be alphabetic with spaces
-			if !isValidAlphaWithSpaces(criteria.FieldValue) {
-				return fmt.Errorf("invalid city format: %s", criteria.FieldValue)
-			}
-		case "phone":
-			// Example: Validate phone should be numeric
-			if !isValidNumeric(criteria.FieldValue) {
-				return fmt.Errorf("invalid phone number format: %s", criteria.FieldValue)
-			}
-		case "height":
-			// Example: Validate height should be a float
-			if !isValidFloat(criteria.FieldValue) {
-				return fmt.Errorf("invalid height format: %s", criteria.FieldValue)
+		for i, value := range values {
+			value = trimWhitespace(value)
+			values[i] = value
+
+			// Validate if field value is empty
+			if value == "" {
+				return fmt.Errorf("field value cannot be empty")
 			}
-		case "married":
-			// Example: Validate married should be a boolean
-			if !isValidBoolean(criteria.FieldValue) {
-				return fmt.Errorf("invalid married format: %s", criteria.FieldValue)
+
+			switch criteria.Operator {
+			case pb.Operator_PREFIX, pb.Operator_CONTAINS, pb.Operator_IEQ, pb.Operator_REGEX:
+				// Pattern operators only apply to text fields and take free-form values
+				if criteria.FieldName != "fname" && criteria.FieldName != "city" {
+					return fmt.Errorf("operator %v is not supported for %s", criteria.Operator, criteria.FieldName)
+				}
+			default:
+				if err := validateFieldValue(criteria.FieldName, value); err != nil {
+					return err
+				}
 			}
-		default:
-			return fmt.Errorf("unsupported field name: %s", criteria.FieldName)
 		}
 	}
 
 	return nil
 }
 
+// validateFieldValue checks that value has the expected format for fieldName.
+func validateFieldValue(fieldName, value string) error {
+	// Additional custom validation rules based on field name (example rules)
+	switch fieldName {
+	case "fname":
+		// Example: Validate first name should be alphabetic
+		if !isValidAlphabetic(value) {
+			return fmt.Errorf("invalid first name format: %s", value)
+		}
+	case "city":
+		// Example: Validate city should be alphabetic with spaces
+		if !isValidAlphaWithSpaces(value) {
+			return fmt.Errorf("invalid city format: %s", value)
+		}
+	case "phone":
+		// Example: Validate phone should be numeric
+		if !isValidNumeric(value) {
+			return fmt.Errorf("invalid phone number format: %s", value)
+		}
+	case "height":
+		// Example: Validate height should be a float
+		if !isValidFloat(value) {
+			return fmt.Errorf("invalid height format: %s", value)
+		}
+	case "married":
+		// Example: Validate married should be a boolean
+		if !isValidBoolean(value) {
+			return fmt.Errorf("invalid married format: %s", value)
+		}
+	default:
+		return fmt.Errorf("unsupported field name: %s", fieldName)
+	}
+	return nil
+}
+
 // Helper function to trim leading and trailing whitespace
 func trimWhitespace(s string) string {
 	return strings.TrimSpace(s)
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/tlsutil"
+)
+
+// certgen writes a self-signed CA with a server and a client certificate for
+// trying out TLS and mutual TLS locally
+func main() {
+	outDir := flag.String("out", "certs", "directory the PEM files are written to")
+	hosts := flag.String("hosts", "localhost,127.0.0.1", "comma-separated DNS names and IPs of the server certificate")
+	client := flag.String("client", "grpc-client", "common name of the client certificate")
+	validFor := flag.Duration("valid-for", 365*24*time.Hour, "validity of the certificates")
+	flag.Parse()
+
+	ca, err := tlsutil.NewCA("grpc-project development CA", *validFor)
+	if err != nil {
+		log.Fatalf("Failed to create CA: %v", err)
+	}
+	serverCert, serverKey, err := ca.IssueServer(strings.Split(*hosts, ","), *validFor)
+	if err != nil {
+		log.Fatalf("Failed to issue server certificate: %v", err)
+	}
+	clientCert, clientKey, err := ca.IssueClient(*client, *validFor)
+	if err != nil {
+		log.Fatalf("Failed to issue client certificate: %v", err)
+	}
+
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		log.Fatalf("Failed to create %s: %v", *outDir, err)
+	}
+	files := []struct {
+		name string
+		data []byte
+		perm os.FileMode
+	}{
+		{"ca.pem", ca.CertPEM, 0o644},
+		{"server.pem", serverCert, 0o644},
+		{"server-key.pem", serverKey, 0o600},
+		{"client.pem", clientCert, 0o644},
+		{"client-key.pem", clientKey, 0o600},
+	}
+	for _, f := range files {
+		path := filepath.Join(*outDir, f.name)
+		if err := os.WriteFile(path, f.data, f.perm); err != nil {
+			log.Fatalf("Failed to write %s: %v", path, err)
+		}
+		log.Printf("Wrote %s", path)
+	}
+}
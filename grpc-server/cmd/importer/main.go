@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/database"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/logger"
+)
+
+// importer seeds or refreshes a bolt store from a JSON, NDJSON, CSV or YAML file of users
+func main() {
+	dbPath := flag.String("db", "data/users.db", "bolt file to import into")
+	jsonPath := flag.String("json", "internal/utils/simulated_entry.json", "file of users to import")
+	format := flag.String("format", "", "format of the users file: json, ndjson, csv or yaml (default detected from the extension)")
+	loadMode := flag.String("mode", database.LoadStrict, "how invalid records are handled: strict or lenient")
+	flag.Parse()
+
+	// Initialize the  custom logger
+	loggerv1, err := logger.InitLogger(logger.Options{Format: logger.FormatConsole})
+	if err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+
+	// Open the store without seeding so every record is imported explicitly below
+	store, err := database.NewBoltStore(*dbPath, "", database.LoadOptions{})
+	if err != nil {
+		log.Fatalf("Failed to open bolt store: %v", err)
+	}
+	defer store.Close()
+
+	n, err := store.ImportFile(context.Background(), *jsonPath, database.LoadOptions{Mode: *loadMode, Format: *format})
+	if err != nil {
+		loggerv1.Errorf("Failed to import %s: %v", *jsonPath, err)
+		return
+	}
+	loggerv1.Infof("Imported %v users from %s into %s", n, *jsonPath, *dbPath)
+}
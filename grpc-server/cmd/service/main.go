@@ -1,84 +1,253 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"net"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
 	httpServer "github.com/ParasJain0307/grpc-project/grpc-server/httpserver"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/auth"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/config"
 	"github.com/ParasJain0307/grpc-project/grpc-server/internal/database"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/lifecycle"
 	"github.com/ParasJain0307/grpc-project/grpc-server/internal/logger"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/metrics"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/policy"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/ratelimit"
 	"github.com/ParasJain0307/grpc-project/grpc-server/internal/service"
-	"github.com/ParasJain0307/grpc-project/grpc-server/internal/utils"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/tlsutil"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/test/bufconn"
 )
 
 func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run starts the service and blocks until it is shut down. It returns its
+// errors rather than exiting so deferred cleanups such as flushing traces and
+// closing the store run before main exits.
+func run() error {
+	// Load the configuration from the config file, environment variables and flags
+	cfg, printOnly, err := config.Load(os.Args[0], os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if printOnly {
+		if err := cfg.Print(os.Stdout); err != nil {
+			return fmt.Errorf("failed to print configuration: %w", err)
+		}
+		return nil
+	}
+
 	// Initialize the  custom logger
-	loggerv1, err := logger.InitLogger()
+	loggerv1, err := logger.InitLogger(cfg.Log.Options())
 	if err != nil {
-		log.Fatalf("Failed to initialize logger: %v", err)
+		return fmt.Errorf("failed to initialize logger: %w", err)
 	}
 
-	// Retrieve JSON file path for simulated entries from environment variable
-	jsonFilePath := os.Getenv("JSON_FILE_PATH")
-	if jsonFilePath == "" {
-		jsonFilePath = "internal/utils/simulated_entry.json"
+	// Export spans of the gateway, the gRPC server and the store
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Options())
+	if err != nil {
+		loggerv1.Errorf("Failed to initialize tracing: %v", err)
+		return fmt.Errorf("failed to initialize tracing: %w", err)
 	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			loggerv1.Errorf("Failed to flush traces: %v", err)
+		}
+	}()
 
-	// Initialize the simulated database
-	db, err := database.NewDatabase(jsonFilePath)
+	// Initialize the user store
+	store, err := database.NewUserStore(database.StoreConfig{
+		Backend:        cfg.Store.Backend,
+		JSONPath:       cfg.Store.File,
+		BoltPath:       cfg.Store.BoltPath,
+		Load:           database.LoadOptions{Mode: cfg.Store.LoadMode, Format: cfg.Store.Format},
+		ReloadInterval: time.Duration(cfg.Store.ReloadInterval),
+	})
 	if err != nil {
-		loggerv1.Errorf("Error while initializing database: %v", err)
-		log.Fatalf("Failed to initialize database: %v", err)
+		loggerv1.Errorf("Error while initializing %s store: %v", cfg.Store.Backend, err)
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+	if err := metrics.RegisterStore(store); err != nil {
+		loggerv1.Errorf("Failed to register dataset metrics: %v", err)
+	}
+
+	// Create a new gRPC server instance, serving TLS when a certificate is configured
+	// Trace every RPC, continuing the W3C trace context sent by callers. Metrics
+	// and request logging come first in the interceptor chains so rejected calls
+	// are counted and logged too.
+	serverOpts := []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(metrics.UnaryServerInterceptor(), logger.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(metrics.StreamServerInterceptor(), logger.StreamServerInterceptor()),
+	}
+	if cfg.GRPC.TLS.Enabled() {
+		tlsConfig, err := tlsutil.NewServerConfig(cfg.GRPC.TLS.Options())
+		if err != nil {
+			loggerv1.Errorf("Failed to load TLS certificates: %v", err)
+			return fmt.Errorf("failed to load TLS certificates: %w", err)
+		}
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		loggerv1.Infof("TLS enabled, mutual TLS %v", cfg.GRPC.TLS.ClientCAFile != "")
 	}
 
-	// Create a new gRPC server instance
-	grpcServer := grpc.NewServer()
+	// Authenticate every RPC when API keys or a JWKS are configured
+	if cfg.Auth.Enabled() {
+		// Probes and load balancers check the health of the server without credentials
+		authOpts := cfg.Auth.Options()
+		authOpts.PublicMethods = append(authOpts.PublicMethods, healthpb.Health_Check_FullMethodName, healthpb.Health_Watch_FullMethodName)
+		authenticator, err := auth.NewAuthenticator(authOpts)
+		if err != nil {
+			loggerv1.Errorf("Failed to initialize authentication: %v", err)
+			return fmt.Errorf("failed to initialize authentication: %w", err)
+		}
+		serverOpts = append(serverOpts,
+			grpc.ChainUnaryInterceptor(authenticator.UnaryServerInterceptor()),
+			grpc.ChainStreamInterceptor(authenticator.StreamServerInterceptor()),
+		)
+		loggerv1.Info("Authentication enabled")
+	}
+
+	// Limit the calls of each client, after authentication identified it
+	if cfg.RateLimit.Enabled() {
+		limiter, err := ratelimit.New(cfg.RateLimit.Options())
+		if err != nil {
+			loggerv1.Errorf("Failed to initialize rate limiting: %v", err)
+			return fmt.Errorf("failed to initialize rate limiting: %w", err)
+		}
+		serverOpts = append(serverOpts,
+			grpc.ChainUnaryInterceptor(limiter.UnaryServerInterceptor()),
+			grpc.ChainStreamInterceptor(limiter.StreamServerInterceptor()),
+		)
+		loggerv1.Infof("Rate limiting enabled, %g calls per second by default", cfg.RateLimit.RPS)
+	}
+
+	// Restrict RPCs and user fields by role, after the caller was authenticated
+	if cfg.Auth.PolicyFile != "" {
+		rbac, err := policy.Load(cfg.Auth.PolicyFile)
+		if err != nil {
+			loggerv1.Errorf("Failed to load authorization policy: %v", err)
+			return fmt.Errorf("failed to load authorization policy: %w", err)
+		}
+		serverOpts = append(serverOpts,
+			grpc.ChainUnaryInterceptor(rbac.UnaryServerInterceptor()),
+			grpc.ChainStreamInterceptor(rbac.StreamServerInterceptor()),
+		)
+		loggerv1.Infof("Authorization policy loaded for roles %v", rbac.Roles())
+	}
+	grpcServer := grpc.NewServer(serverOpts...)
 
 	// Register your service implementation with the gRPC server
-	pb.RegisterUserServiceServer(grpcServer, &service.UserService{
-		Database: *db,
-	})
+	pb.RegisterUserServiceServer(grpcServer, service.NewService(store))
 
-	// Start listening for incoming connections on port :50051
-	listener, err := net.Listen("tcp", utils.GRPCSERVERPORT)
-	if err != nil {
-		loggerv1.Errorf("Failed to listen: %v", err)
-		log.Fatalf("Failed to listen: %v", err)
+	// Report the health of the server as a whole ("") and of the UserService. The
+	// store has loaded its data by now, so both are SERVING until shutdown.
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(pb.UserService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+
+	// Describe the registered services to tools such as grpcurl. Reflection calls
+	// need credentials like any other call when authentication is enabled.
+	if cfg.GRPC.Reflection {
+		reflection.Register(grpcServer)
+		loggerv1.Info("Server reflection enabled")
 	}
 
-	// Log the gRPC server start
-	loggerv1.Infof("gRPC server is listening on port %s", utils.GRPCSERVERPORT)
+	// Start listening for incoming connections on the configured address, unless
+	// gRPC is only served on the HTTP address
+	var listeners []net.Listener
+	if cfg.GRPC.Address != "" {
+		listener, err := net.Listen("tcp", cfg.GRPC.Address)
+		if err != nil {
+			loggerv1.Errorf("Failed to listen: %v", err)
+			return fmt.Errorf("failed to listen: %w", err)
+		}
+		loggerv1.Infof("gRPC server is listening on %s", cfg.GRPC.Address)
+		listeners = append(listeners, listener)
+	}
 
-	// Handle OS signals for graceful shutdown of gRPC server
-	go func() {
-		sig := make(chan os.Signal, 1)
-		signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
-		s := <-sig
-		loggerv1.Infof("Received signal %v. Gracefully shutting down gRPC server...", s)
-		grpcServer.GracefulStop()
-	}()
+	// The gateway calls the gRPC server in-process through an in-memory listener,
+	// unless it is told to dial http.grpc_target
+	var inProcess *bufconn.Listener
+	if cfg.HTTP.GRPCTarget == "" {
+		inProcess = bufconn.Listen(inProcessBufferSize)
+		listeners = append(listeners, inProcess)
+	}
+	gatewayConn, err := httpServer.Dial(cfg.HTTP, inProcess)
+	if err != nil {
+		loggerv1.Errorf("Failed to connect the gateway to the gRPC server: %v", err)
+		return fmt.Errorf("failed to connect the gateway to the gRPC server: %w", err)
+	}
+	defer gatewayConn.Close()
+	gateway, err := httpServer.HttpServer(cfg.HTTP, httpServer.Options{Conn: gatewayConn})
+	if err != nil {
+		loggerv1.Errorf("Failed to initialize the HTTP gateway: %v", err)
+		return fmt.Errorf("failed to initialize the HTTP gateway: %w", err)
+	}
+	httpListener, err := net.Listen("tcp", cfg.HTTP.Address)
+	if err != nil {
+		loggerv1.Errorf("Failed to listen on the HTTP address: %v", err)
+		return fmt.Errorf("failed to listen on the HTTP address: %w", err)
+	}
 
-	// Start the gRPC server in a separate goroutine
+	// Serve until SIGINT or SIGTERM, then stop routing new traffic here and let
+	// in-flight calls complete, the gateway's first as they call the gRPC server
+	lifecycleOpts := cfg.Shutdown.Options()
+	lifecycleOpts.OnShutdown = healthServer.Shutdown
+	manager := lifecycle.New(lifecycleOpts)
+	// With multiplexing, the HTTP port hands its HTTP/2 connections to the gRPC
+	// server, which then owns them like those of its own listeners
+	var multiplexer lifecycle.Component
+	if cfg.HTTP.MultiplexGRPC {
+		var grpcListener net.Listener
+		multiplexer, grpcListener, httpListener = lifecycle.Multiplexer("HTTP port multiplexer", httpListener)
+		listeners = append(listeners, grpcListener)
+		loggerv1.Infof("gRPC is also served on the HTTP address %s", cfg.HTTP.Address)
+	}
+	manager.Add(lifecycle.GRPCServer("gRPC server", grpcServer, listeners...))
+	manager.Add(lifecycle.HTTPServer("HTTP gateway", gateway, httpListener))
+	if cfg.HTTP.MultiplexGRPC {
+		manager.Add(multiplexer)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	go func() {
-		if err := grpcServer.Serve(listener); err != nil {
-			loggerv1.Errorf("Failed to serve gRPC server: %v", err)
-			log.Fatalf("Failed to serve gRPC server: %v", err)
-		}
+		// A second signal kills the process instead of waiting for the drain
+		<-ctx.Done()
+		stop()
 	}()
-	// Calling HttpServer for exposing endpoint to the server asynchronise
-	go httpServer.HttpServer()
-
-	waitForSignal()
+	if err := manager.Run(ctx); err != nil {
+		loggerv1.Errorf("Server stopped with an error: %v", err)
+		return err
+	}
+	loggerv1.Info("Server stopped")
+	return nil
 }
 
-// waitForSignal blocks until SIGINT or SIGTERM signal is received
-func waitForSignal() {
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
-	<-sig
-}
+// inProcessBufferSize is the buffer of the in-memory connections between the
+// gateway and the gRPC server
+const inProcessBufferSize = 1 << 20
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// tokengen signs HS256 development tokens with the first oct key of a JWKS
+// file, creating the file with a random key when it does not exist yet
+func main() {
+	jwksFile := flag.String("jwks", "certs/jwks.json", "JWKS file holding the HS256 signing key")
+	subject := flag.String("sub", "developer", "subject of the token")
+	roles := flag.String("roles", "", "comma-separated roles of the subject")
+	issuer := flag.String("iss", "", "issuer of the token, omitted when empty")
+	audience := flag.String("aud", "", "audience of the token, omitted when empty")
+	ttl := flag.Duration("ttl", time.Hour, "lifetime of the token")
+	flag.Parse()
+
+	kid, secret, err := signingKey(*jwksFile)
+	if err != nil {
+		log.Fatalf("Failed to load signing key: %v", err)
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub": *subject,
+		"iat": now.Unix(),
+		"exp": now.Add(*ttl).Unix(),
+	}
+	if *roles != "" {
+		claims["roles"] = strings.Split(*roles, ",")
+	}
+	if *issuer != "" {
+		claims["iss"] = *issuer
+	}
+	if *audience != "" {
+		claims["aud"] = *audience
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(secret)
+	if err != nil {
+		log.Fatalf("Failed to sign token: %v", err)
+	}
+	fmt.Println(signed)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	K   string `json:"k"`
+}
+
+// signingKey returns the first HS256 key of path, writing a new key set when the file is missing
+func signingKey(path string) (string, []byte, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return "", nil, err
+		}
+		key := jwk{Kty: "oct", Kid: "dev", Alg: "HS256", Use: "sig", K: base64.RawURLEncoding.EncodeToString(secret)}
+		set.Keys = []jwk{key}
+		data, err := json.MarshalIndent(set, "", "  ")
+		if err != nil {
+			return "", nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return "", nil, err
+		}
+		if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+			return "", nil, err
+		}
+		log.Printf("Wrote %s", path)
+		return key.Kid, secret, nil
+	case err != nil:
+		return "", nil, err
+	}
+
+	if err := json.Unmarshal(data, &set); err != nil {
+		return "", nil, fmt.Errorf("error parsing %s: %v", path, err)
+	}
+	for _, key := range set.Keys {
+		if key.Kty != "oct" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+		secret, err := base64.RawURLEncoding.DecodeString(key.K)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid key %q: %v", key.Kid, err)
+		}
+		return key.Kid, secret, nil
+	}
+	return "", nil, fmt.Errorf("%s has no HS256 key", path)
+}
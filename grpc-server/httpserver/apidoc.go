@@ -0,0 +1,363 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Paths of the documents describing the API
+const (
+	DescriptorSetPath = "/api/descriptor.pb"  // Compiled FileDescriptorSet of user.proto and its imports
+	OpenAPIPath       = "/api/openapi.json"   // OpenAPI 3 document of the REST routes
+	openAPIVersion    = "3.0.3"               // Version of the OpenAPI specification followed
+	apiTitle          = "User Management API" // info.title of the OpenAPI document
+	apiPrefix         = "/v1/"                // Routes outside of it are the original ones, documented as deprecated
+)
+
+// pathParam matches the variables of a path template, e.g. {user.id} or {name=users/*}
+var pathParam = regexp.MustCompile(`\{([^}=]+)(=[^}]*)?\}`)
+
+// descriptorSet returns the serialized FileDescriptorSet of user.proto and every
+// file it imports, dependencies first, as expected by grpcurl -protoset
+func descriptorSet() ([]byte, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := make(map[string]bool)
+	var add func(protoreflect.FileDescriptor)
+	add = func(file protoreflect.FileDescriptor) {
+		if seen[file.Path()] {
+			return
+		}
+		seen[file.Path()] = true
+		imports := file.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			add(imports.Get(i).FileDescriptor)
+		}
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(file))
+	}
+	add(pb.File_user_proto)
+	return proto.Marshal(set)
+}
+
+// schemaSet collects the component schemas of an OpenAPI document
+type schemaSet struct {
+	defs       map[string]interface{}
+	protoNames bool // Properties are named as in user.proto rather than in lowerCamelCase
+}
+
+// propertyName returns the name the gateway writes field under
+func (s *schemaSet) propertyName(field protoreflect.FieldDescriptor) string {
+	if s.protoNames {
+		return string(field.Name())
+	}
+	return field.JSONName()
+}
+
+// openAPIDocument generates the OpenAPI document of the REST routes of service from
+// the google.api.http options of its methods, deriving the schemas from the
+// message descriptors. Fields are named as the gateway writes them, in lowerCamelCase
+// unless protoNames is set.
+func openAPIDocument(service protoreflect.ServiceDescriptor, protoNames bool) ([]byte, error) {
+	schemas := &schemaSet{protoNames: protoNames, defs: map[string]interface{}{
+		"Status": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"code":    map[string]interface{}{"type": "integer", "format": "int32"},
+				"message": map[string]interface{}{"type": "string"},
+				"details": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "object"}},
+			},
+		},
+	}}
+	paths := make(map[string]map[string]interface{})
+	methods := service.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		method := methods.Get(i)
+		rule, _ := proto.GetExtension(method.Options(), annotations.E_Http).(*annotations.HttpRule)
+		if rule == nil {
+			continue
+		}
+		fullMethod := "/" + string(service.FullName()) + "/" + string(method.Name())
+		for n, binding := range append([]*annotations.HttpRule{rule}, rule.GetAdditionalBindings()...) {
+			verb, template := httpPattern(binding)
+			if template == "" {
+				continue
+			}
+			// Operations are named after their RPC, numbered for additional bindings
+			operationID := string(method.Name())
+			if n > 0 {
+				operationID = fmt.Sprintf("%s_%d", operationID, n)
+			}
+			op, err := operation(fullMethod, method, binding, template, schemas)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", fullMethod, err)
+			}
+			op["operationId"] = operationID
+			op["tags"] = []string{string(service.FullName())}
+			op["x-grpc-method"] = fullMethod
+			if !strings.HasPrefix(template, apiPrefix) {
+				op["deprecated"] = true
+			}
+			path := pathParam.ReplaceAllString(template, "{$1}")
+			if paths[path] == nil {
+				paths[path] = make(map[string]interface{})
+			}
+			paths[path][strings.ToLower(verb)] = op
+		}
+	}
+
+	return json.MarshalIndent(map[string]interface{}{
+		"openapi": openAPIVersion,
+		"info": map[string]interface{}{
+			"title":   apiTitle,
+			"version": string(service.ParentFile().Package()),
+		},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": schemas.defs},
+	}, "", "  ")
+}
+
+// httpPattern returns the HTTP method and path template of rule
+func httpPattern(rule *annotations.HttpRule) (string, string) {
+	switch pattern := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		return http.MethodGet, pattern.Get
+	case *annotations.HttpRule_Put:
+		return http.MethodPut, pattern.Put
+	case *annotations.HttpRule_Post:
+		return http.MethodPost, pattern.Post
+	case *annotations.HttpRule_Delete:
+		return http.MethodDelete, pattern.Delete
+	case *annotations.HttpRule_Patch:
+		return http.MethodPatch, pattern.Patch
+	case *annotations.HttpRule_Custom:
+		return pattern.Custom.GetKind(), pattern.Custom.GetPath()
+	}
+	return "", ""
+}
+
+// operation documents the binding of method to the path template: the fields of
+// the request bound to the path, the body and the query, and the response
+func operation(fullMethod string, method protoreflect.MethodDescriptor, binding *annotations.HttpRule, template string, schemas *schemaSet) (map[string]interface{}, error) {
+	input := method.Input()
+	var params []map[string]interface{}
+	bound := make(map[string]bool) // Top-level request fields taken from the path or the body
+	for _, match := range pathParam.FindAllStringSubmatch(template, -1) {
+		field, err := fieldByPath(input, match[1])
+		if err != nil {
+			return nil, err
+		}
+		bound[strings.SplitN(match[1], ".", 2)[0]] = true
+		params = append(params, map[string]interface{}{
+			"name": match[1], "in": "path", "required": true, "schema": fieldSchema(field, schemas),
+		})
+	}
+
+	op := make(map[string]interface{})
+	switch body := binding.GetBody(); body {
+	case "":
+	case "*":
+		op["requestBody"] = jsonContent(schemaRef(input, schemas))
+	default:
+		field := input.Fields().ByName(protoreflect.Name(body))
+		if field == nil {
+			return nil, fmt.Errorf("unknown body field %q", body)
+		}
+		bound[body] = true
+		op["requestBody"] = jsonContent(valueSchema(field, schemas))
+	}
+	// Every other scalar field can be set in the query; repeated ones by repeating the parameter
+	if binding.GetBody() != "*" {
+		fields := input.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			field := fields.Get(i)
+			if bound[string(field.Name())] || (field.Kind() == protoreflect.MessageKind && !isFieldMask(field.Message())) {
+				continue
+			}
+			params = append(params, map[string]interface{}{
+				"name": string(field.Name()), "in": "query", "schema": valueSchema(field, schemas),
+			})
+		}
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	var output map[string]interface{}
+	if responseBody := binding.GetResponseBody(); responseBody != "" {
+		field := method.Output().Fields().ByName(protoreflect.Name(responseBody))
+		if field == nil {
+			return nil, fmt.Errorf("unknown response body field %q", responseBody)
+		}
+		output = valueSchema(field, schemas)
+	} else {
+		output = schemaRef(method.Output(), schemas)
+	}
+	success, statusCode := map[string]interface{}{"description": "OK"}, http.StatusOK
+	if method.IsStreamingServer() {
+		success = jsonContent(map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"result": output,
+				"error":  map[string]interface{}{"$ref": "#/components/schemas/Status"},
+			},
+		})
+		success["description"] = "One JSON object per line, Server-Sent Events with Accept: " + eventStreamType +
+			", length-delimited messages with Accept: " + protobufType + ", or CSV rows with Accept: " + csvType
+	} else {
+		success = jsonContent(output)
+		if binding.GetResponseBody() == "" {
+			addMediaTypes(success, method.Output())
+		}
+		// See writeCreated
+		if fullMethod == pb.UserService_CreateUser_FullMethodName {
+			statusCode = http.StatusCreated
+		}
+		success["description"] = http.StatusText(statusCode)
+	}
+	failure := jsonContent(map[string]interface{}{"$ref": "#/components/schemas/Status"})
+	failure["description"] = "The status of the failed call, with the HTTP status closest to its gRPC code"
+	op["responses"] = map[string]interface{}{fmt.Sprint(statusCode): success, "default": failure}
+	return op, nil
+}
+
+// fieldByPath resolves a dotted field path of a path template, e.g. user.id
+func fieldByPath(msg protoreflect.MessageDescriptor, path string) (protoreflect.FieldDescriptor, error) {
+	var field protoreflect.FieldDescriptor
+	for _, name := range strings.Split(path, ".") {
+		if msg == nil {
+			return nil, fmt.Errorf("path parameter %q goes through a scalar field", path)
+		}
+		if field = msg.Fields().ByName(protoreflect.Name(name)); field == nil {
+			return nil, fmt.Errorf("unknown path parameter %q", path)
+		}
+		msg = field.Message()
+	}
+	return field, nil
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"content": map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}},
+	}
+}
+
+// addMediaTypes documents the encodings of msg besides JSON in response, which
+// clients pick with the Accept header
+func addMediaTypes(response map[string]interface{}, msg protoreflect.MessageDescriptor) {
+	content := response["content"].(map[string]interface{})
+	content[protobufType] = map[string]interface{}{"schema": map[string]interface{}{
+		"type": "string", "format": "binary", "description": "Serialized " + string(msg.FullName()),
+	}}
+	if isUser(msg) || userListField(msg) != nil {
+		content[csvType] = map[string]interface{}{"schema": map[string]interface{}{
+			"type": "string", "description": "A header row, then a row per user",
+		}}
+	}
+}
+
+// schemaName names the component schema of a message or enum, e.g. FilterGroup.Logic
+func schemaName(desc protoreflect.Descriptor) string {
+	return strings.TrimPrefix(string(desc.FullName()), string(desc.ParentFile().Package())+".")
+}
+
+// schemaRef returns a reference to the schema of msg, adding it and the schemas
+// of its fields to schemas when missing
+func schemaRef(msg protoreflect.MessageDescriptor, schemas *schemaSet) map[string]interface{} {
+	name := schemaName(msg)
+	ref := map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	if _, ok := schemas.defs[name]; ok {
+		return ref
+	}
+	properties := make(map[string]interface{})
+	// Reserve the name first, as messages such as SearchFilter are recursive
+	schemas.defs[name] = map[string]interface{}{"type": "object", "properties": properties}
+	fields := msg.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		properties[schemas.propertyName(field)] = valueSchema(field, schemas)
+	}
+	return ref
+}
+
+// valueSchema returns the schema of field, an array for repeated fields
+func valueSchema(field protoreflect.FieldDescriptor, schemas *schemaSet) map[string]interface{} {
+	schema := fieldSchema(field, schemas)
+	if field.IsList() {
+		schema = map[string]interface{}{"type": "array", "items": schema}
+	}
+	return schema
+}
+
+// isFieldMask reports whether msg is google.protobuf.FieldMask, written as a
+// comma-separated string of paths
+func isFieldMask(msg protoreflect.MessageDescriptor) bool {
+	return msg.FullName() == "google.protobuf.FieldMask"
+}
+
+// fieldSchema returns the schema of a single value of field
+func fieldSchema(field protoreflect.FieldDescriptor, schemas *schemaSet) map[string]interface{} {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]interface{}{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		// Written as strings, as JSON numbers cannot hold every 64-bit integer
+		return map[string]interface{}{"type": "string", "format": "int64"}
+	case protoreflect.FloatKind:
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case protoreflect.DoubleKind:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case protoreflect.BytesKind:
+		return map[string]interface{}{"type": "string", "format": "byte"}
+	case protoreflect.EnumKind:
+		enum := field.Enum()
+		name := schemaName(enum)
+		if _, ok := schemas.defs[name]; !ok {
+			values := enum.Values()
+			names := make([]string, 0, values.Len())
+			numbers := make([]string, 0, values.Len())
+			for i := 0; i < values.Len(); i++ {
+				value := values.Get(i)
+				names = append(names, string(value.Name()))
+				numbers = append(numbers, fmt.Sprintf("%s=%d", value.Name(), value.Number()))
+			}
+			schemas.defs[name] = map[string]interface{}{
+				"type":        "string",
+				"enum":        names,
+				"description": "Also accepted as its number: " + strings.Join(numbers, ", "),
+			}
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		if isFieldMask(field.Message()) {
+			return map[string]interface{}{"type": "string", "format": "field-mask"}
+		}
+		return schemaRef(field.Message(), schemas)
+	default:
+		return map[string]interface{}{"type": "string"}
+	}
+}
+
+// serveDocument serves a document generated once at startup
+func serveDocument(contentType string, data []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Write(data)
+	}
+}
@@ -0,0 +1,330 @@
+package httpserver
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Media types of the responses, negotiated with the Accept header
+const (
+	jsonType     = "application/json"
+	protobufType = "application/x-protobuf"
+	csvType      = "text/csv"
+)
+
+// negotiable lists the media types the gateway writes, in the order preferred
+// when the Accept header ranks several of them equally
+var negotiable = []string{jsonType, protobufType, csvType, eventStreamType}
+
+// newJSONMarshaler returns the marshaler of JSON requests and responses, writing
+// messages with opts in the proto3 JSON mapping
+func newJSONMarshaler(opts protojson.MarshalOptions) *runtime.JSONPb {
+	return &runtime.JSONPb{
+		MarshalOptions:   opts,
+		UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+	}
+}
+
+// negotiate replaces the Accept header of r with the media type the response is
+// written in, as the gateway only matches Accept headers holding exactly one of
+// its media types. Without a preference the response is encoded like the request
+// body, and defaults to JSON. CSV responses start with the header row of csv.
+func negotiate(next http.Handler, csv *csvMarshaler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept := r.Header.Values("Accept")
+		if len(accept) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		mediaType := preferredType(strings.Join(accept, ","))
+		if mediaType == "" {
+			// Nothing acceptable is written anyway, so the default beats a 406
+			r.Header.Del("Accept")
+		} else {
+			r.Header.Set("Accept", mediaType)
+		}
+		if mediaType == csvType {
+			w = &csvHeaderWriter{ResponseWriter: w, header: csv.header}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// preferredType returns the negotiable media type of accept with the highest
+// quality, the first of negotiable among those of equal quality, or "" when it
+// accepts any type or none of them
+func preferredType(accept string) string {
+	best, bestQuality := -1, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || mediaType == "*/*" {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		for i, candidate := range negotiable {
+			if candidate != mediaType && !(strings.HasSuffix(mediaType, "/*") && strings.HasPrefix(candidate, strings.TrimSuffix(mediaType, "*"))) {
+				continue
+			}
+			if quality > bestQuality || (quality == bestQuality && i < best) {
+				best, bestQuality = i, quality
+			}
+		}
+	}
+	if best < 0 {
+		return ""
+	}
+	return negotiable[best]
+}
+
+// protobufMarshaler writes messages in the protobuf binary encoding. The messages
+// of a server stream are length-delimited, with a varint size before each one; a
+// failure ends the stream with a google.rpc.Status in place of a user.
+type protobufMarshaler struct {
+	runtime.ProtoMarshaller
+}
+
+// ContentType implements runtime.Marshaler
+func (*protobufMarshaler) ContentType(interface{}) string {
+	return protobufType
+}
+
+// Marshal unwraps the {"result": ...} and {"error": ...} chunks of server streams
+func (m *protobufMarshaler) Marshal(v interface{}) ([]byte, error) {
+	msg := streamChunk(v)
+	if msg == nil {
+		return m.ProtoMarshaller.Marshal(v)
+	}
+	var buf bytes.Buffer
+	if _, err := protodelim.MarshalTo(&buf, msg); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Delimiter adds nothing between the messages of a stream, as they carry their size
+func (*protobufMarshaler) Delimiter() []byte {
+	return []byte{}
+}
+
+// csvMarshaler writes users, single or in a list, as CSV rows with a column per
+// User field; csvHeaderWriter adds the header row. Other messages, such as the
+// statuses of failed calls, are written as JSON.
+type csvMarshaler struct {
+	json    *runtime.JSONPb
+	columns []protoreflect.FieldDescriptor
+	header  []byte
+}
+
+// newCSVMarshaler names the columns of the header like json names the fields
+func newCSVMarshaler(json *runtime.JSONPb) *csvMarshaler {
+	m := &csvMarshaler{json: json}
+	fields := (&pb.User{}).ProtoReflect().Descriptor().Fields()
+	names := make([]string, 0, fields.Len())
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		m.columns = append(m.columns, field)
+		if json.UseProtoNames {
+			names = append(names, string(field.Name()))
+		} else {
+			names = append(names, field.JSONName())
+		}
+	}
+	m.header = csvRows([][]string{names})
+	return m
+}
+
+// ContentType implements runtime.Marshaler
+func (m *csvMarshaler) ContentType(v interface{}) string {
+	if chunk := streamChunk(v); chunk != nil {
+		v = chunk
+	}
+	if msg, ok := v.(proto.Message); ok && userRows(msg) != nil {
+		return csvType
+	}
+	return m.json.ContentType(v)
+}
+
+// Marshal writes the users of v as rows, unwrapping the chunks of server streams.
+// A failure ending a stream is written as a line of JSON.
+func (m *csvMarshaler) Marshal(v interface{}) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	chunk := streamChunk(v)
+	if chunk != nil {
+		msg, ok = chunk, true
+	}
+	if !ok {
+		return m.json.Marshal(v)
+	}
+	users := userRows(msg)
+	if users == nil {
+		return m.json.Marshal(msg)
+	}
+	records := make([][]string, 0, len(users))
+	for _, user := range users {
+		record := make([]string, len(m.columns))
+		for i, field := range m.columns {
+			record[i] = csvValue(field, user.Get(field))
+		}
+		records = append(records, record)
+	}
+	data := csvRows(records)
+	if chunk != nil {
+		// The gateway adds the line ending after each message of a stream
+		data = bytes.TrimSuffix(data, []byte("\n"))
+	}
+	return data, nil
+}
+
+// Unmarshal implements runtime.Marshaler; requests cannot be written in CSV
+func (*csvMarshaler) Unmarshal([]byte, interface{}) error {
+	return errors.New("CSV request bodies are not supported")
+}
+
+// NewDecoder implements runtime.Marshaler
+func (m *csvMarshaler) NewDecoder(io.Reader) runtime.Decoder {
+	return runtime.DecoderFunc(func(v interface{}) error { return m.Unmarshal(nil, v) })
+}
+
+// NewEncoder implements runtime.Marshaler
+func (m *csvMarshaler) NewEncoder(w io.Writer) runtime.Encoder {
+	return runtime.EncoderFunc(func(v interface{}) error {
+		data, err := m.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	})
+}
+
+// Delimiter ends each row of a stream
+func (*csvMarshaler) Delimiter() []byte {
+	return []byte("\n")
+}
+
+// csvRows encodes records as CSV lines, quoting fields as needed
+func csvRows(records [][]string) []byte {
+	var buf bytes.Buffer
+	// Writing to a buffer with the default separator cannot fail
+	csv.NewWriter(&buf).WriteAll(records)
+	return buf.Bytes()
+}
+
+// csvValue formats a User field as in its JSON encoding, without quotes
+func csvValue(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	switch field.Kind() {
+	case protoreflect.FloatKind:
+		return strconv.FormatFloat(value.Float(), 'g', -1, 32)
+	case protoreflect.DoubleKind:
+		return strconv.FormatFloat(value.Float(), 'g', -1, 64)
+	default:
+		return value.String()
+	}
+}
+
+// userRows returns the users msg holds: itself when it is a User, the elements
+// of its repeated User field when it has one, nil otherwise
+func userRows(msg proto.Message) []protoreflect.Message {
+	m := msg.ProtoReflect()
+	if field := userListField(m.Descriptor()); field != nil {
+		list := m.Get(field).List()
+		users := make([]protoreflect.Message, list.Len())
+		for i := range users {
+			users[i] = list.Get(i).Message()
+		}
+		return users
+	}
+	if isUser(m.Descriptor()) {
+		return []protoreflect.Message{m}
+	}
+	return nil
+}
+
+// userListField returns the repeated User field of msg, such as UsersList.users
+func userListField(msg protoreflect.MessageDescriptor) protoreflect.FieldDescriptor {
+	fields := msg.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if field.IsList() && field.Message() != nil && isUser(field.Message()) {
+			return field
+		}
+	}
+	return nil
+}
+
+func isUser(msg protoreflect.MessageDescriptor) bool {
+	return msg.FullName() == (&pb.User{}).ProtoReflect().Descriptor().FullName()
+}
+
+// streamChunk returns the message of a {"result": ...} or {"error": ...} chunk
+// the gateway writes for each message of a server stream, or nil
+func streamChunk(v interface{}) proto.Message {
+	switch chunk := v.(type) {
+	case map[string]interface{}:
+		msg, _ := chunk["result"].(proto.Message)
+		return msg
+	case map[string]proto.Message:
+		if st, ok := chunk["error"].(*spb.Status); ok {
+			return st
+		}
+	}
+	return nil
+}
+
+// csvHeaderWriter writes the CSV header row before the body of a CSV response,
+// counting it in the Content-Length of single messages
+type csvHeaderWriter struct {
+	http.ResponseWriter
+	header      []byte
+	wroteHeader bool // WriteHeader was called
+	isCSV       bool // The response is CSV, rather than a JSON status
+	wroteRow    bool // The header row was written
+}
+
+func (w *csvHeaderWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.isCSV = strings.HasPrefix(w.Header().Get("Content-Type"), csvType)
+		if length, err := strconv.Atoi(w.Header().Get("Content-Length")); err == nil && w.isCSV {
+			w.Header().Set("Content-Length", strconv.Itoa(length+len(w.header)))
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *csvHeaderWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.isCSV && !w.wroteRow {
+		w.wroteRow = true
+		if _, err := w.ResponseWriter.Write(w.header); err != nil {
+			return 0, err
+		}
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Unwrap lets the gateway flush the rows of a stream as they are written
+func (w *csvHeaderWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,264 @@
+package httpserver
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestPreferredType(t *testing.T) {
+	tests := []struct {
+		accept, want string
+	}{
+		{"application/json", jsonType},
+		{"text/csv", csvType},
+		{"application/x-protobuf", protobufType},
+		{"text/event-stream", eventStreamType},
+
+		// Quality values
+		{"text/csv;q=0.5, application/x-protobuf", protobufType},
+		{"text/csv, application/x-protobuf;q=0.9", csvType},
+		{"application/json;q=0.1, text/csv;q=0.2", csvType},
+		{"text/csv;q=0, application/json;q=0.1", jsonType},
+		{"text/csv;q=0", ""},
+
+		// Equal qualities are broken by the order of negotiable, not of the header
+		{"text/csv, application/json", jsonType},
+		{"text/event-stream, application/x-protobuf", protobufType},
+		{"text/csv;q=0.5, application/x-protobuf;q=0.5", protobufType},
+
+		// Wildcards
+		{"text/*", csvType},
+		{"application/*", jsonType},
+		{"application/*;q=0.5, text/event-stream", eventStreamType},
+		{"*/*", ""},
+		{"*/*, text/csv;q=0.1", csvType},
+		{"image/*", ""},
+
+		// Unparseable entries and types the gateway does not write are skipped
+		{"text/csv;q=high, application/x-protobuf;q=0.1", protobufType},
+		{"not a type, text/csv", csvType},
+		{"text/html, application/xml", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := preferredType(tt.accept); got != tt.want {
+			t.Errorf("preferredType(%q) = %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestNegotiateRewritesAccept(t *testing.T) {
+	csv := newCSVMarshaler(newJSONMarshaler(protojson.MarshalOptions{}))
+	tests := []struct {
+		name   string
+		accept []string
+		want   []string // Accept headers seen by the gateway
+	}{
+		{"no Accept header", nil, nil},
+		{"single type", []string{"application/x-protobuf"}, []string{protobufType}},
+		{"several headers", []string{"text/html", "text/csv;q=0.5, application/json;q=0.2"}, []string{csvType}},
+		{"nothing acceptable", []string{"text/html"}, nil},
+		{"any type", []string{"*/*"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			handler := negotiate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Values("Accept")
+			}), csv)
+			r := httptest.NewRequest(http.MethodGet, "/v1/users/1", nil)
+			for _, accept := range tt.accept {
+				r.Header.Add("Accept", accept)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+			if len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+				t.Errorf("gateway saw Accept %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNegotiateWritesCSVHeaderRow(t *testing.T) {
+	csv := newCSVMarshaler(newJSONMarshaler(protojson.MarshalOptions{}))
+	header := "id,fname,city,phone,height,married\n"
+	if string(csv.header) != header {
+		t.Fatalf("header row = %q, want %q", csv.header, header)
+	}
+	protoNames := newCSVMarshaler(newJSONMarshaler(protojson.MarshalOptions{UseProtoNames: true}))
+	if string(protoNames.header) != header {
+		t.Fatalf("header row with proto names = %q, want %q", protoNames.header, header)
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		writes      []string
+		want        string
+	}{
+		{"single message", csvType, []string{"1,John,Oslo,1,180.5,true\n"}, header + "1,John,Oslo,1,180.5,true\n"},
+		{"stream of rows", csvType, []string{"1,John,Oslo,1,180.5,true", "\n", "2,Jane,Oslo,2,165,false", "\n"},
+			header + "1,John,Oslo,1,180.5,true\n2,Jane,Oslo,2,165,false\n"},
+		{"JSON status", "application/json", []string{`{"code":5}`}, `{"code":5}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := negotiate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				if len(tt.writes) == 1 {
+					w.Header().Set("Content-Length", strconv.Itoa(len(tt.writes[0])))
+				}
+				for _, data := range tt.writes {
+					io.WriteString(w, data)
+				}
+			}), csv)
+			r := httptest.NewRequest(http.MethodGet, "/v1/users/1", nil)
+			r.Header.Set("Accept", "text/csv")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+			if got := rec.Body.String(); got != tt.want {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+			if length := rec.Header().Get("Content-Length"); length != "" && length != strconv.Itoa(len(tt.want)) {
+				t.Errorf("Content-Length = %s, want %d", length, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestCSVHeaderWriterCountsHeaderRowInContentLength(t *testing.T) {
+	header := []byte("id,fname\n")
+	for _, tt := range []struct {
+		contentType, want string
+	}{
+		{csvType, "19"},
+		{jsonType, "10"},
+	} {
+		rec := httptest.NewRecorder()
+		w := &csvHeaderWriter{ResponseWriter: rec, header: header}
+		w.Header().Set("Content-Type", tt.contentType)
+		w.Header().Set("Content-Length", "10")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("1,John\n"))
+		if got := rec.Header().Get("Content-Length"); got != tt.want {
+			t.Errorf("%s: Content-Length = %s, want %s", tt.contentType, got, tt.want)
+		}
+		if rec.Code != http.StatusCreated {
+			t.Errorf("%s: status = %d, want 201", tt.contentType, rec.Code)
+		}
+	}
+}
+
+func TestCSVMarshaler(t *testing.T) {
+	m := newCSVMarshaler(newJSONMarshaler(protojson.MarshalOptions{}))
+	john := &pb.User{Id: 1, Fname: "John", City: "New York, NY", Phone: 1234567890, Height: 180.3, Married: true}
+	jane := &pb.User{Id: 2, Fname: "Jane", City: "Oslo", Phone: 2, Height: 165}
+	tests := []struct {
+		name        string
+		v           interface{}
+		contentType string
+		want        string // Compared as JSON for JSON content
+	}{
+		{"user", john, csvType, "1,John,\"New York, NY\",1234567890,180.3,true\n"},
+		{"list of users", &pb.UsersList{Users: []*pb.User{john, jane}}, csvType,
+			"1,John,\"New York, NY\",1234567890,180.3,true\n2,Jane,Oslo,2,165,false\n"},
+		{"message of a stream", map[string]interface{}{"result": jane}, csvType, "2,Jane,Oslo,2,165,false"},
+		{"status", &spb.Status{Code: int32(codes.NotFound), Message: "user not found"}, jsonType,
+			`{"code":5,"message":"user not found"}`},
+		{"failure ending a stream", map[string]proto.Message{"error": &spb.Status{Code: int32(codes.Internal)}}, jsonType,
+			`{"code":13}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.ContentType(tt.v); got != tt.contentType {
+				t.Errorf("ContentType = %q, want %q", got, tt.contentType)
+			}
+			data, err := m.Marshal(tt.v)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			// protojson varies its whitespace, so statuses are compared decoded
+			if tt.contentType == jsonType {
+				got, want := &spb.Status{}, &spb.Status{}
+				if err := protojson.Unmarshal(data, got); err != nil {
+					t.Fatalf("decoding %s: %v", data, err)
+				}
+				if err := protojson.Unmarshal([]byte(tt.want), want); err != nil {
+					t.Fatal(err)
+				}
+				if !proto.Equal(got, want) {
+					t.Errorf("Marshal = %s, want %s", data, tt.want)
+				}
+				return
+			}
+			if string(data) != tt.want {
+				t.Errorf("Marshal = %q, want %q", data, tt.want)
+			}
+		})
+	}
+	if err := m.Unmarshal([]byte("1,John"), &pb.User{}); err == nil {
+		t.Error("CSV request body accepted")
+	}
+}
+
+func TestProtobufMarshalerDelimitsStreams(t *testing.T) {
+	m := &protobufMarshaler{}
+	users := []*pb.User{{Id: 1, Fname: "John"}, {Id: 2, Fname: "Jane", Married: true}}
+	failure := &spb.Status{Code: int32(codes.Unavailable), Message: "store unavailable"}
+
+	// A stream as the gateway writes it: each chunk followed by the delimiter
+	var body bytes.Buffer
+	for _, chunk := range []interface{}{
+		map[string]interface{}{"result": users[0]},
+		map[string]interface{}{"result": users[1]},
+		map[string]proto.Message{"error": failure},
+	} {
+		data, err := m.Marshal(chunk)
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		body.Write(data)
+		body.Write(m.Delimiter())
+	}
+
+	r := bufio.NewReader(&body)
+	for _, want := range users {
+		got := &pb.User{}
+		if err := protodelim.UnmarshalFrom(r, got); err != nil {
+			t.Fatalf("reading user: %v", err)
+		}
+		if !proto.Equal(got, want) {
+			t.Errorf("read %v, want %v", got, want)
+		}
+	}
+	st := &spb.Status{}
+	if err := protodelim.UnmarshalFrom(r, st); err != nil || !proto.Equal(st, failure) {
+		t.Errorf("read status %v, %v; want %v", st, err, failure)
+	}
+	if _, err := r.ReadByte(); err != io.EOF {
+		t.Errorf("data left after the stream: %v", err)
+	}
+
+	// A single message is not delimited
+	data, err := m.Marshal(users[0])
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if want, _ := proto.Marshal(users[0]); !bytes.Equal(data, want) {
+		t.Errorf("single message = %x, want %x", data, want)
+	}
+	if m.ContentType(users[0]) != protobufType {
+		t.Errorf("ContentType = %q", m.ContentType(users[0]))
+	}
+}
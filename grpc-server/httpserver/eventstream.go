@@ -0,0 +1,57 @@
+package httpserver
+
+import (
+	"fmt"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// eventStreamType is the media type of Server-Sent Events, answered to requests
+// with Accept: text/event-stream such as those of EventSource clients
+const eventStreamType = "text/event-stream"
+
+// eventStream writes each message of a server stream as a Server-Sent Event:
+// users as "user" events and a failure as an "error" event
+type eventStream struct {
+	runtime.JSONPb
+}
+
+// newEventStream encodes the data of events like json
+func newEventStream(json *runtime.JSONPb) *eventStream {
+	return &eventStream{*json}
+}
+
+// ContentType implements runtime.Marshaler
+func (*eventStream) ContentType(interface{}) string {
+	return eventStreamType
+}
+
+// Marshal unwraps the {"result": ...} and {"error": ...} chunks of the gateway
+// into the data of an event
+func (m *eventStream) Marshal(v interface{}) ([]byte, error) {
+	event := "message"
+	switch chunk := v.(type) {
+	case map[string]interface{}:
+		if result, ok := chunk["result"]; ok {
+			event, v = "user", result
+		}
+	case map[string]proto.Message:
+		if st, ok := chunk["error"]; ok {
+			event, v = "error", st
+		}
+	case *spb.Status:
+		event = "error"
+	}
+	data, err := m.JSONPb.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("event: %s\ndata: %s", event, data)), nil
+}
+
+// Delimiter ends each event with a blank line
+func (*eventStream) Delimiter() []byte {
+	return []byte("\n\n")
+}
@@ -0,0 +1,365 @@
+package httpserver
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/config"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/database"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/service"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// gatewayUsers returns the users held by the service behind the test gateway
+func gatewayUsers() []*pb.User {
+	return []*pb.User{
+		{Id: 1, Fname: "John", City: "New York", Phone: 1234567890, Height: 180.5, Married: true},
+		{Id: 2, Fname: "Jane", City: "Los Angeles", Phone: 2345678901, Height: 165, Married: false},
+		{Id: 3, Fname: "Emily", City: "Chicago", Phone: 3456789012, Height: 170.2, Married: false},
+		{Id: 4, Fname: "Mike", City: "New York", Phone: 4567890123, Height: 175, Married: true},
+	}
+}
+
+// serveUsers serves a UserService holding gatewayUsers in-process until the test
+// ends, returning the connection of a gateway to it
+func serveUsers(t *testing.T) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	pb.RegisterUserServiceServer(server, service.NewService(database.NewDatabaseFromUsers(gatewayUsers(), true)))
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := Dial(config.HTTPConfig{}, lis)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// newGateway returns the handler of a gateway in front of serveUsers
+func newGateway(t *testing.T) http.Handler {
+	t.Helper()
+	return newGatewayWith(t, config.Default().HTTP)
+}
+
+// newGatewayWith returns the handler of a gateway configured by cfg in front of
+// serveUsers
+func newGatewayWith(t *testing.T, cfg config.HTTPConfig) http.Handler {
+	t.Helper()
+	srv, err := HttpServer(cfg, Options{Conn: serveUsers(t)})
+	if err != nil {
+		t.Fatalf("HttpServer: %v", err)
+	}
+	return srv.Handler
+}
+
+// serve sends a request for method and target with body to handler
+func serve(handler http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	var r *http.Request
+	if body == "" {
+		r = httptest.NewRequest(method, target, nil)
+	} else {
+		r = httptest.NewRequest(method, target, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	return rec
+}
+
+// userIDs returns the IDs of the users in a JSON response holding a user or a
+// list of users
+func userIDs(t *testing.T, body []byte) []int32 {
+	t.Helper()
+	decoder := protojson.UnmarshalOptions{DiscardUnknown: true}
+	list := &pb.UsersList{}
+	if err := decoder.Unmarshal(body, list); err == nil && len(list.Users) > 0 {
+		ids := make([]int32, len(list.Users))
+		for i, user := range list.Users {
+			ids[i] = user.Id
+		}
+		return ids
+	}
+	user := &pb.User{}
+	if err := decoder.Unmarshal(body, user); err != nil {
+		t.Fatalf("decoding %s: %v", body, err)
+	}
+	if user.Id == 0 {
+		return nil
+	}
+	return []int32{user.Id}
+}
+
+// streamedIDs returns the IDs of the users of a JSON stream, one {"result": user}
+// chunk per line, failing on a chunk holding an error
+func streamedIDs(t *testing.T, body io.Reader) []int32 {
+	t.Helper()
+	var ids []int32
+	lines := bufio.NewScanner(body)
+	for lines.Scan() {
+		var chunk struct {
+			Result json.RawMessage `json:"result"`
+			Error  json.RawMessage `json:"error"`
+		}
+		if err := json.Unmarshal(lines.Bytes(), &chunk); err != nil {
+			t.Fatalf("decoding chunk %s: %v", lines.Bytes(), err)
+		}
+		if chunk.Error != nil {
+			t.Fatalf("stream failed: %s", chunk.Error)
+		}
+		user := &pb.User{}
+		if err := protojson.Unmarshal(chunk.Result, user); err != nil {
+			t.Fatalf("decoding user %s: %v", chunk.Result, err)
+		}
+		ids = append(ids, user.Id)
+	}
+	return ids
+}
+
+func TestGatewayRoutes(t *testing.T) {
+	newYork := `{"criterias": [{"field_name": "city", "field_value": "New York"}]}`
+	tests := []struct {
+		name, method, target, body string
+		status                     int
+		ids                        []int32
+	}{
+		{"get user", http.MethodGet, "/v1/users/2", "", http.StatusOK, []int32{2}},
+		{"get user by its original route", http.MethodGet, "/user/2", "", http.StatusOK, []int32{2}},
+		{"unknown user", http.MethodGet, "/v1/users/9", "", http.StatusNotFound, nil},
+		{"invalid user ID", http.MethodGet, "/v1/users/john", "", http.StatusBadRequest, nil},
+		{"batch get", http.MethodGet, "/v1/users:batchGet?user_ids=3&user_ids=1", "", http.StatusOK, []int32{1, 3}},
+		{"search", http.MethodPost, "/v1/users:search", newYork, http.StatusOK, []int32{1, 4}},
+		{"search page", http.MethodPost, "/v1/users:search",
+			`{"criterias": [{"field_name": "city", "field_value": "New York"}], "page_size": 1, "order_by": "id desc"}`, http.StatusOK, []int32{4}},
+		{"search of an unknown field", http.MethodPost, "/v1/users:search", `{"criterias": [{"field_name": "age", "field_value": "3"}]}`,
+			http.StatusBadRequest, nil},
+		{"create user", http.MethodPost, "/v1/users", `{"fname": "Ann", "city": "Oslo", "phone": "5", "height": 160}`, http.StatusCreated, []int32{5}},
+		{"create user by its original route", http.MethodPost, "/user", `{"fname": "Ann", "city": "Oslo", "phone": "5", "height": 160}`,
+			http.StatusCreated, []int32{5}},
+		{"create existing user", http.MethodPost, "/v1/users", `{"id": 1, "fname": "Ann", "city": "Oslo", "phone": "5", "height": 160}`,
+			http.StatusConflict, nil},
+		{"update user", http.MethodPatch, "/v1/users/2", `{"city": "Oslo"}`, http.StatusOK, []int32{2}},
+		{"replace user", http.MethodPut, "/user/2", `{"fname": "Jane", "city": "Oslo", "phone": "2", "height": 165}`, http.StatusOK, []int32{2}},
+		{"update unknown user", http.MethodPatch, "/v1/users/9", `{"city": "Oslo"}`, http.StatusNotFound, nil},
+		{"delete user", http.MethodDelete, "/v1/users/3", "", http.StatusOK, nil},
+		{"delete user by its original route", http.MethodDelete, "/user/3", "", http.StatusOK, nil},
+		{"delete unknown user", http.MethodDelete, "/v1/users/9", "", http.StatusNotFound, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(newGateway(t), tt.method, tt.target, tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tt.status, rec.Body)
+			}
+			if rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+				return
+			}
+			if ids := userIDs(t, rec.Body.Bytes()); !slices.Equal(ids, tt.ids) {
+				t.Errorf("users = %v, want %v", ids, tt.ids)
+			}
+		})
+	}
+}
+
+func TestGatewayWritesUsers(t *testing.T) {
+	gateway := newGateway(t)
+	get := func(id string) *pb.User {
+		t.Helper()
+		rec := serve(gateway, http.MethodGet, "/v1/users/"+id, "")
+		if rec.Code == http.StatusNotFound {
+			return nil
+		}
+		user := &pb.User{}
+		if err := protojson.Unmarshal(rec.Body.Bytes(), user); err != nil {
+			t.Fatalf("decoding %s: %v", rec.Body, err)
+		}
+		return user
+	}
+
+	if rec := serve(gateway, http.MethodPost, "/v1/users", `{"fname": "Ann", "city": "Oslo", "phone": "5", "height": 160}`); rec.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, want 201", rec.Code)
+	}
+	if user := get("5"); user.GetFname() != "Ann" || user.GetCity() != "Oslo" {
+		t.Errorf("created user = %v", user)
+	}
+
+	// PATCH updates the fields of the body, leaving the others alone
+	if rec := serve(gateway, http.MethodPatch, "/v1/users/2", `{"city": "Oslo"}`); rec.Code != http.StatusOK {
+		t.Fatalf("update: status = %d, want 200", rec.Code)
+	}
+	if user := get("2"); user.GetCity() != "Oslo" || user.GetFname() != "Jane" || user.GetPhone() != 2345678901 {
+		t.Errorf("updated user = %v, want only the city changed", user)
+	}
+
+	if rec := serve(gateway, http.MethodDelete, "/v1/users/3", ""); rec.Code != http.StatusOK {
+		t.Fatalf("delete: status = %d, want 200", rec.Code)
+	}
+	if user := get("3"); user != nil {
+		t.Errorf("deleted user = %v, want not found", user)
+	}
+}
+
+func TestGatewayLegacyRoutes(t *testing.T) {
+	newYork := `[{"field_name": "city", "field_value": "New York"}]`
+	tests := []struct {
+		name, method, target, body string
+		status                     int
+		ids                        []int32
+		headers                    map[string]string
+	}{
+		{"comma-separated IDs", http.MethodGet, "/users/3,1", "", http.StatusOK, []int32{1, 3}, map[string]string{"X-Total-Count": "2"}},
+		{"single ID", http.MethodGet, "/users/2", "", http.StatusOK, []int32{2}, nil},
+		{"IDs with paging", http.MethodGet, "/users/1,2,3?page_size=2", "", http.StatusOK, []int32{1, 2}, map[string]string{"X-Total-Count": "3"}},
+		{"invalid ID", http.MethodGet, "/users/1,john", "", http.StatusBadRequest, nil, nil},
+		{"unknown IDs", http.MethodGet, "/users/8,9", "", http.StatusOK, nil, map[string]string{"X-Total-Count": "0"}},
+
+		{"search with an array", http.MethodPost, "/users/search", newYork, http.StatusOK, []int32{1, 4}, map[string]string{"X-Total-Count": "2"}},
+		{"search with an object", http.MethodPost, "/users/search",
+			`{"filter": {"group": {"logic": "OR", "filters": [{"criteria": {"field_name": "fname", "field_value": "Jane"}}, {"criteria": {"field_name": "city", "field_value": "Chicago"}}]}}}`,
+			http.StatusOK, []int32{2, 3}, nil},
+		{"search page", http.MethodPost, "/users/search?page_size=1&order_by=id%20desc", newYork, http.StatusOK, []int32{4},
+			map[string]string{"X-Total-Count": "2"}},
+		{"search with operators", http.MethodPost, "/users/search", `[{"field_name": "height", "field_value": "175", "operator": "GTE"}]`,
+			http.StatusOK, []int32{1, 4}, nil},
+		{"malformed body", http.MethodPost, "/users/search", `[{"field_name": "city"`, http.StatusBadRequest, nil, nil},
+		{"unknown operator", http.MethodPost, "/users/search", `[{"field_name": "city", "operator": "LIKE"}]`, http.StatusBadRequest, nil, nil},
+		{"invalid page size", http.MethodPost, "/users/search?page_size=ten", newYork, http.StatusBadRequest, nil, nil},
+		{"unknown field", http.MethodPost, "/users/search", `[{"field_name": "age", "field_value": "3"}]`, http.StatusBadRequest, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(newGateway(t), tt.method, tt.target, tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tt.status, rec.Body)
+			}
+			for name, want := range tt.headers {
+				if got := rec.Header().Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			if ids := userIDs(t, rec.Body.Bytes()); !slices.Equal(ids, tt.ids) {
+				t.Errorf("users = %v, want %v", ids, tt.ids)
+			}
+		})
+	}
+}
+
+func TestGatewayStreamSearch(t *testing.T) {
+	newYork := `[{"field_name": "city", "field_value": "New York"}]`
+	tests := []struct {
+		name, method, target, body string
+		status                     int
+		ids                        []int32
+	}{
+		{"transcoded route", http.MethodPost, "/v1/users:streamSearch", `{"criterias": ` + newYork + `}`, http.StatusOK, []int32{1, 4}},
+		{"criteria in the body", http.MethodPost, "/users/search/stream", newYork, http.StatusOK, []int32{1, 4}},
+		{"criteria in the query", http.MethodGet, "/users/search/stream?q=" + url.QueryEscape(newYork), "", http.StatusOK, []int32{1, 4}},
+		{"every user", http.MethodGet, "/users/search/stream", "", http.StatusOK, []int32{1, 2, 3, 4}},
+		{"empty body", http.MethodPost, "/users/search/stream", " ", http.StatusOK, []int32{1, 2, 3, 4}},
+		{"malformed body", http.MethodPost, "/users/search/stream", `{"criterias": `, http.StatusBadRequest, nil},
+		{"malformed query", http.MethodGet, "/users/search/stream?q=%5B", "", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(newGateway(t), tt.method, tt.target, tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tt.status, rec.Body)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			ids := streamedIDs(t, rec.Body)
+			slices.Sort(ids)
+			if !slices.Equal(ids, tt.ids) {
+				t.Errorf("streamed users = %v, want %v", ids, tt.ids)
+			}
+		})
+	}
+}
+
+func TestGatewayLimitsLegacyBodies(t *testing.T) {
+	cfg := config.Default().HTTP
+	cfg.MaxBodyBytes = 64
+	gateway := newGatewayWith(t, cfg)
+	fits := `[{"field_name": "city", "field_value": "Chicago"}]`
+	tooLarge := `[{"field_name": "city", "field_value": "` + strings.Repeat("x", 64) + `"}]`
+
+	tests := []struct {
+		name, target, body string
+		status             int
+	}{
+		{"search within the limit", "/users/search", fits, http.StatusOK},
+		{"search over the limit", "/users/search", tooLarge, http.StatusRequestEntityTooLarge},
+		{"stream within the limit", "/users/search/stream", fits, http.StatusOK},
+		{"stream over the limit", "/users/search/stream", tooLarge, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(gateway, http.MethodPost, tt.target, tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tt.status, rec.Body)
+			}
+			if rec.Code == http.StatusOK {
+				return
+			}
+			// Encoded like the other errors of the gateway
+			body := &spb.Status{}
+			if err := protojson.Unmarshal(rec.Body.Bytes(), body); err != nil {
+				t.Fatalf("decoding body %s: %v", rec.Body, err)
+			}
+			if codes.Code(body.GetCode()) != codes.InvalidArgument || !strings.Contains(body.GetMessage(), "exceeds 64 bytes") {
+				t.Errorf("body = %v, want the limit", body)
+			}
+		})
+	}
+}
+
+// failingMarshaler fails to marshal users, still writing the statuses of errors
+type failingMarshaler struct {
+	runtime.JSONPb
+}
+
+func (m *failingMarshaler) Marshal(v interface{}) ([]byte, error) {
+	if _, ok := v.(*pb.User); ok {
+		return nil, errors.New("cannot marshal users")
+	}
+	return m.JSONPb.Marshal(v)
+}
+
+func TestCreatedStatusFollowsMarshalling(t *testing.T) {
+	gateway := runtime.NewServeMux(
+		runtime.WithErrorHandler(handleGRPCError),
+		runtime.WithForwardResponseOption(writeCreated),
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &failingMarshaler{}),
+	)
+	if err := pb.RegisterUserServiceHandler(context.Background(), gateway, serveUsers(t)); err != nil {
+		t.Fatal(err)
+	}
+	rec := serve(withCreatedStatus(gateway), http.MethodPost, "/v1/users", `{"fname": "Ann", "city": "Oslo", "phone": "5", "height": 160}`)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500 for a response that failed to marshal", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"code":`) {
+		t.Errorf("body = %s, want the status of the error", rec.Body)
+	}
+}
@@ -4,134 +4,319 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"net"
 	"net/http"
 	"strconv"
-	"strings"
+	"time"
 
 	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
-	"github.com/ParasJain0307/grpc-project/grpc-server/internal/utils"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/config"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/logger"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/metrics"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/ratelimit"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/tlsutil"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
 )
 
-const (
-	grpcServerAddress = utils.GRPCSERVERADDR + utils.GRPCSERVERPORT // Address of your gRPC server
-	httpServerPort    = utils.HTTPSERVERPORT                        // Port on which the HTTP server will listen
-)
+// Options configures HttpServer
+type Options struct {
+	Conn *grpc.ClientConn // Connection the gateway calls the gRPC server through, see Dial
+}
+
+// Dial connects the gateway to the gRPC server: over the network to cfg.GRPCTarget
+// when it is set, otherwise in-process through inProcess, a listener served by the
+// gRPC server of this process. The client handler injects the trace context of
+// each request into the gRPC metadata.
+func Dial(cfg config.HTTPConfig, inProcess *bufconn.Listener) (*grpc.ClientConn, error) {
+	if cfg.GRPCTarget == "" {
+		// Calls still go through the interceptors of the server, only without a socket
+		return grpc.NewClient("passthrough:///in-process",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return inProcess.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithStatsHandler(otelgrpc.NewClientHandler()))
+	}
 
-func HttpServer() {
+	// Over TLS when the gRPC server requires it
+	creds := insecure.NewCredentials()
+	if cfg.GRPCTLS.Enabled {
+		tlsConfig, err := tlsutil.NewClientConfig(cfg.GRPCTarget, cfg.GRPCTLS.Options())
+		if err != nil {
+			return nil, fmt.Errorf("error loading gateway TLS certificates: %v", err)
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	return grpc.NewClient(cfg.GRPCTarget, grpc.WithTransportCredentials(creds), grpc.WithStatsHandler(otelgrpc.NewClientHandler()))
+}
+
+// HttpServer returns the server of the REST gateway on cfg.Address, calling the
+// gRPC server through opts.Conn. The caller serves it and closes opts.Conn once
+// it is shut down.
+func HttpServer(cfg config.HTTPConfig, opts Options) (*http.Server, error) {
 	mux := http.NewServeMux()
+	grpcConn := opts.Conn
 
-	// Create a gRPC client connection
-	grpcConn, err := grpc.Dial(grpcServerAddress, grpc.WithInsecure())
-	if err != nil {
-		log.Fatalf("Failed to dial gRPC server: %v", err)
+	// Messages are written in JSON by default, or in the media type negotiated
+	// with the Accept header
+	jsonMarshaler := newJSONMarshaler(cfg.JSON.MarshalOptions())
+	csvMarshaler := newCSVMarshaler(jsonMarshaler)
+
+	// REST routes transcoded from the google.api.http options of user.proto, plus
+	// the original routes they cannot express
+	gateway := runtime.NewServeMux(
+		runtime.WithMetadata(gatewayMetadata),
+		runtime.WithOutgoingHeaderMatcher(func(key string) (string, bool) {
+			// withRequestID already echoes the request ID
+			if key == logger.RequestIDKey {
+				return "", false
+			}
+			return runtime.MetadataHeaderPrefix + key, true
+		}),
+		runtime.WithErrorHandler(handleGRPCError),
+		runtime.WithForwardResponseOption(writeCreated),
+		runtime.WithForwardResponseOption(writePageHeaders),
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.HTTPBodyMarshaler{Marshaler: jsonMarshaler}),
+		runtime.WithMarshalerOption(jsonType, jsonMarshaler),
+		runtime.WithMarshalerOption(protobufType, &protobufMarshaler{}),
+		runtime.WithMarshalerOption(csvType, csvMarshaler),
+		runtime.WithMarshalerOption(eventStreamType, newEventStream(jsonMarshaler)),
+	)
+	if err := pb.RegisterUserServiceHandler(context.Background(), gateway, grpcConn); err != nil {
+		return nil, fmt.Errorf("failed to register the REST routes: %v", err)
+	}
+	if err := registerLegacyRoutes(gateway, int64(cfg.MaxBodyBytes)); err != nil {
+		return nil, fmt.Errorf("failed to register the original REST routes: %v", err)
 	}
-	defer grpcConn.Close()
+	mux.Handle("/", withCreatedStatus(negotiate(gateway, csvMarshaler)))
 
-	client := pb.NewUserServiceClient(grpcConn)
+	// Runtime log level, opt-in as the gateway does not authenticate it
+	if cfg.LogLevelEndpoint {
+		mux.Handle("/loglevel", logger.LevelHandler())
+	}
 
-	// Handler for /user/:id endpoint
-	mux.HandleFunc("/user/", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	// Liveness: the gateway is up and the gRPC server answers health checks, even
+	// while it is draining. Readiness: the gRPC server reports SERVING.
+	health := healthpb.NewHealthClient(grpcConn)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		servingStatus, err := checkHealth(r.Context(), health)
+		if err != nil {
+			writeJSONStatus(w, http.StatusServiceUnavailable, map[string]string{"status": "DOWN", "error": err.Error()})
 			return
 		}
-
-		userIDStr := r.URL.Path[len("/user/"):]
-		userID, err := strconv.Atoi(userIDStr)
+		writeJSONResponse(w, map[string]string{"status": "UP", "grpc": servingStatus.String()})
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		servingStatus, err := checkHealth(r.Context(), health)
 		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			writeJSONStatus(w, http.StatusServiceUnavailable, map[string]string{"status": "NOT_READY", "error": err.Error()})
 			return
 		}
-
-		req := &pb.GetUserByIDRequest{UserId: int32(userID)}
-		user, err := client.GetUserByID(context.Background(), req)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to get user: %v", err), http.StatusInternalServerError)
+		if servingStatus != healthpb.HealthCheckResponse_SERVING {
+			writeJSONStatus(w, http.StatusServiceUnavailable, map[string]string{"status": "NOT_READY", "grpc": servingStatus.String()})
 			return
 		}
-
-		writeJSONResponse(w, user)
+		writeJSONResponse(w, map[string]string{"status": "READY", "grpc": servingStatus.String()})
 	})
 
-	// Handler for /users/:ids endpoint
-	mux.HandleFunc("/users/", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
-		}
+	// Self-describing API: the descriptor set lets grpcurl work without user.proto
+	descriptors, err := descriptorSet()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build the descriptor set: %v", err)
+	}
+	openAPI, err := openAPIDocument(pb.File_user_proto.Services().ByName("UserService"), cfg.JSON.UseProtoNames)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the OpenAPI document: %v", err)
+	}
+	mux.HandleFunc(DescriptorSetPath, serveDocument("application/x-protobuf", descriptors))
+	mux.HandleFunc(OpenAPIPath, serveDocument("application/json", openAPI))
 
-		userIDsStr := r.URL.Path[len("/users/"):]
-		userIDs := strings.Split(userIDsStr, ",")
+	// Prometheus metrics of the gateway and the gRPC server running in this process
+	mux.Handle(metrics.Path, metrics.Handler())
 
-		var userIDsInt32 []int32
-		for _, idStr := range userIDs {
-			id, err := strconv.Atoi(idStr)
-			if err != nil {
-				http.Error(w, fmt.Sprintf("Invalid user ID: %s", idStr), http.StatusBadRequest)
-				return
-			}
-			userIDsInt32 = append(userIDsInt32, int32(id))
-		}
+	// Record the metrics of every route and trace each request as a continuation
+	// of the W3C traceparent header when present
+	handler := otelhttp.NewHandler(withRequestID(metrics.InstrumentMux(mux)), "gateway",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			_, route := mux.Handler(r)
+			return r.Method + " " + route
+		}),
+		// Scrapes and probes would drown out the traces of real requests
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return r.URL.Path != metrics.Path && r.URL.Path != "/healthz" && r.URL.Path != "/readyz"
+		}))
+	return &http.Server{
+		Addr:    cfg.Address,
+		Handler: handler,
+	}, nil
+}
 
-		req := &pb.GetUsersByIDRequest{UserIds: userIDsInt32}
-		usersList, err := client.GetUsersByID(context.Background(), req)
-		if err != nil {
-			handleGRPCError(w, err)
-			return
-		}
+// gatewayMetadata is called once the route of a REST request is known. It labels
+// the metrics and the span of r with the route and forwards the request ID; the
+// gateway itself forwards the Authorization header and the client IP, in
+// x-forwarded-for, which lets the rate limiter tell HTTP clients apart.
+func gatewayMetadata(ctx context.Context, r *http.Request) metadata.MD {
+	if pattern, ok := runtime.HTTPPathPattern(ctx); ok {
+		metrics.SetRoute(ctx, pattern)
+		trace.SpanFromContext(ctx).SetName(r.Method + " " + pattern)
+	}
+	md := metadata.MD{}
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		md.Set(logger.RequestIDKey, id)
+	}
+	return md
+}
 
-		writeJSONResponse(w, usersList)
-	})
+// checkHealth asks the gRPC server for the status of the UserService, giving up
+// after healthTimeout so a hung server fails the probe
+func checkHealth(ctx context.Context, health healthpb.HealthClient) (healthpb.HealthCheckResponse_ServingStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, healthTimeout)
+	defer cancel()
+	resp, err := health.Check(ctx, &healthpb.HealthCheckRequest{Service: pb.UserService_ServiceDesc.ServiceName})
+	if err != nil {
+		return healthpb.HealthCheckResponse_UNKNOWN, err
+	}
+	return resp.GetStatus(), nil
+}
 
-	// Handler for /users/search endpoint
-	mux.HandleFunc("/users/search", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
-		}
+// healthTimeout bounds the health check behind /healthz and /readyz
+const healthTimeout = 2 * time.Second
 
-		var criterias []*pb.SearchCriteria
-		if err := json.NewDecoder(r.Body).Decode(&criterias); err != nil {
-			http.Error(w, fmt.Sprintf("Failed to parse request body: %v", err), http.StatusBadRequest)
-			return
-		}
+type requestIDKey struct{}
 
-		req := &pb.SearchUsersRequest{Criterias: criterias}
-		usersList, err := client.SearchUsers(context.Background(), req)
-		if err != nil {
-			handleGRPCError(w, err)
-			return
+// withRequestID gives every request an ID, taken from its X-Request-ID header or
+// generated, which is echoed in the response and forwarded to the gRPC server
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(logger.RequestIDKey)
+		if !logger.ValidRequestID(id) {
+			id = logger.NewRequestID()
 		}
-
-		writeJSONResponse(w, usersList)
+		w.Header().Set(logger.RequestIDKey, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
 	})
+}
 
-	// Start HTTP server
-	server := &http.Server{
-		Addr:    httpServerPort,
-		Handler: mux,
-	}
-
-	log.Printf("Starting HTTP server on port %s...\n", httpServerPort)
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("Failed to start HTTP server: %v", err)
-	}
+// writeJSONResponse writes the plain JSON object of a probe; messages of the API
+// are always written by the marshalers of the gateway
+func writeJSONResponse(w http.ResponseWriter, data map[string]string) {
+	writeJSONStatus(w, http.StatusOK, data)
 }
 
-func writeJSONResponse(w http.ResponseWriter, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
+func writeJSONStatus(w http.ResponseWriter, statusCode int, data map[string]string) {
 	jsonData, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to marshal JSON: %v", err), http.StatusInternalServerError)
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
 	w.Write(jsonData)
 }
 
-func handleGRPCError(w http.ResponseWriter, err error) {
-	st, _ := status.FromError(err)
-	http.Error(w, fmt.Sprintf("Failed to execute gRPC request: %v", st.Message()), http.StatusInternalServerError)
+type createdKey struct{}
+
+// withCreatedStatus lets writeCreated turn the status of a response into 201
+// Created, see createdWriter
+func withCreatedStatus(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cw := &createdWriter{ResponseWriter: w}
+		next.ServeHTTP(cw, r.WithContext(context.WithValue(r.Context(), createdKey{}, cw)))
+	})
+}
+
+// writeCreated answers CreateUser with 201 Created instead of 200 OK. It runs
+// before the response is marshalled, so it only marks it: a response failing to
+// marshal keeps the status of its error.
+func writeCreated(ctx context.Context, _ http.ResponseWriter, resp proto.Message) error {
+	if method, ok := runtime.RPCMethod(ctx); ok && method == pb.UserService_CreateUser_FullMethodName && resp != nil {
+		if cw, ok := ctx.Value(createdKey{}).(*createdWriter); ok {
+			cw.created = true
+		}
+	}
+	return nil
+}
+
+// createdWriter writes 201 Created in place of the 200 OK of a response marked by
+// writeCreated, leaving error statuses alone
+type createdWriter struct {
+	http.ResponseWriter
+	created     bool // writeCreated marked the response
+	wroteHeader bool // WriteHeader was called
+}
+
+func (w *createdWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if w.created && code == http.StatusOK {
+			code = http.StatusCreated
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *createdWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Unwrap lets the gateway flush the messages of a stream as they are written
+func (w *createdWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// writePageHeaders repeats the paging fields of user lists in the X-Next-Page-Token
+// and X-Total-Count headers, as CSV responses have no room for them
+func writePageHeaders(_ context.Context, w http.ResponseWriter, resp proto.Message) error {
+	if list, ok := resp.(*pb.UsersList); ok {
+		if token := list.GetNextPageToken(); token != "" {
+			w.Header().Set("X-Next-Page-Token", token)
+		}
+		w.Header().Set("X-Total-Count", strconv.Itoa(int(list.GetTotalSize())))
+	}
+	return nil
+}
+
+// handleGRPCError writes the status of a failed call as JSON, with the HTTP status
+// closest to its gRPC code, asking for credentials on Unauthenticated and telling
+// rate limited clients when to retry
+func handleGRPCError(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	st := status.Convert(err)
+	if st.Code() == codes.Unauthenticated {
+		w = challengeWriter{w}
+	}
+	for _, detail := range st.Details() {
+		if retry, ok := detail.(*errdetails.RetryInfo); ok {
+			w.Header().Set("Retry-After", strconv.Itoa(ratelimit.RetryAfterSeconds(retry.GetRetryDelay().AsDuration())))
+		}
+	}
+	runtime.DefaultHTTPErrorHandler(ctx, mux, marshaler, w, r, err)
+}
+
+// challengeWriter sets the authentication challenge of 401 responses, replacing
+// the status message the default error handler puts in WWW-Authenticate
+type challengeWriter struct {
+	http.ResponseWriter
+}
+
+func (w challengeWriter) WriteHeader(code int) {
+	if code == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="grpc-project"`)
+	}
+	w.ResponseWriter.WriteHeader(code)
 }
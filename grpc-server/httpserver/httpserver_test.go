@@ -0,0 +1,81 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// statusWith returns a status error of code carrying details
+func statusWith(t *testing.T, code codes.Code, details ...protoadapt.MessageV1) error {
+	t.Helper()
+	st := status.New(code, code.String())
+	for _, detail := range details {
+		var err error
+		if st, err = st.WithDetails(detail); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return st.Err()
+}
+
+func TestHandleGRPCError(t *testing.T) {
+	marshaler := newJSONMarshaler(protojson.MarshalOptions{})
+	tests := []struct {
+		name       string
+		err        error
+		httpStatus int
+		headers    map[string]string
+	}{
+		{"invalid argument", statusWith(t, codes.InvalidArgument), http.StatusBadRequest, nil},
+		{"not found", statusWith(t, codes.NotFound), http.StatusNotFound, nil},
+		{"already exists", statusWith(t, codes.AlreadyExists), http.StatusConflict, nil},
+		{"permission denied", statusWith(t, codes.PermissionDenied), http.StatusForbidden, nil},
+		{"unauthenticated", statusWith(t, codes.Unauthenticated), http.StatusUnauthorized,
+			map[string]string{"WWW-Authenticate": `Bearer realm="grpc-project"`}},
+		{"rate limited", statusWith(t, codes.ResourceExhausted, &errdetails.RetryInfo{RetryDelay: durationpb.New(1200 * time.Millisecond)}),
+			http.StatusTooManyRequests, map[string]string{"Retry-After": "2"}},
+		{"rate limited for less than a second", statusWith(t, codes.ResourceExhausted, &errdetails.RetryInfo{RetryDelay: durationpb.New(time.Millisecond)}),
+			http.StatusTooManyRequests, map[string]string{"Retry-After": "1"}},
+		{"unavailable", statusWith(t, codes.Unavailable), http.StatusServiceUnavailable, map[string]string{"Retry-After": ""}},
+		{"canceled", statusWith(t, codes.Canceled), 499, nil},
+		{"deadline exceeded", statusWith(t, codes.DeadlineExceeded), http.StatusGatewayTimeout, nil},
+		{"internal", statusWith(t, codes.Internal), http.StatusInternalServerError, map[string]string{"WWW-Authenticate": ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/v1/users/1", nil)
+			handleGRPCError(context.Background(), runtime.NewServeMux(), marshaler, rec, r, tt.err)
+
+			if rec.Code != tt.httpStatus {
+				t.Errorf("HTTP status = %d, want %d", rec.Code, tt.httpStatus)
+			}
+			for name, want := range tt.headers {
+				if got := rec.Header().Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+			// The body is the status, details included
+			body := &spb.Status{}
+			if err := protojson.Unmarshal(rec.Body.Bytes(), body); err != nil {
+				t.Fatalf("decoding body %s: %v", rec.Body, err)
+			}
+			want := status.Convert(tt.err).Proto()
+			if codes.Code(body.GetCode()) != status.Code(tt.err) || body.GetMessage() != want.GetMessage() || len(body.GetDetails()) != len(want.GetDetails()) {
+				t.Errorf("body = %v, want %v", body, want)
+			}
+		})
+	}
+}
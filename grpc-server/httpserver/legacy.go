@@ -0,0 +1,187 @@
+package httpserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// registerLegacyRoutes keeps the original routes that the google.api.http options
+// of user.proto cannot express working, by rewriting them into requests for their
+// /v1 equivalents served by gateway. Their bodies are read up to maxBodyBytes.
+func registerLegacyRoutes(gateway *runtime.ServeMux, maxBodyBytes int64) error {
+	// Comma-separated IDs in the path, now repeated user_ids query parameters
+	err := gateway.HandlePath(http.MethodGet, "/users/{ids}", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		query := r.URL.Query()
+		for _, id := range strings.Split(params["ids"], ",") {
+			query.Add("user_ids", id)
+		}
+		forward(gateway, w, r, http.MethodGet, "/v1/users:batchGet", query, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	// A plain array of criteria as the body and paging in the query parameters
+	err = gateway.HandlePath(http.MethodPost, "/users/search", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		body, ok := readBody(gateway, w, r, maxBodyBytes)
+		if !ok {
+			return
+		}
+		req, err := parseSearchRequest(body)
+		if err != nil {
+			badRequest(gateway, w, r, "Failed to parse request body: %v", err)
+			return
+		}
+		if err := parsePageParams(r, &req.PageSize, &req.PageToken, &req.OrderBy); err != nil {
+			badRequest(gateway, w, r, "Invalid paging parameters: %v", err)
+			return
+		}
+		forwardSearch(gateway, w, r, "/v1/users:search", req)
+	})
+	if err != nil {
+		return err
+	}
+
+	// The same bodies as /users/search, or for EventSource clients, which can only
+	// issue GET requests, in the q query parameter; an empty search streams every user
+	streamSearch := func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		var body []byte
+		if r.Method == http.MethodGet {
+			body = []byte(r.URL.Query().Get("q"))
+		} else {
+			var ok bool
+			if body, ok = readBody(gateway, w, r, maxBodyBytes); !ok {
+				return
+			}
+		}
+		req := &pb.SearchUsersRequest{}
+		if len(bytes.TrimSpace(body)) > 0 {
+			var err error
+			if req, err = parseSearchRequest(body); err != nil {
+				badRequest(gateway, w, r, "Failed to parse request body: %v", err)
+				return
+			}
+		}
+		forwardSearch(gateway, w, r, "/v1/users:streamSearch", req)
+	}
+	if err := gateway.HandlePath(http.MethodGet, "/users/search/stream", streamSearch); err != nil {
+		return err
+	}
+	return gateway.HandlePath(http.MethodPost, "/users/search/stream", streamSearch)
+}
+
+// readBody reads the body of r, answering r with 413 Request Entity Too Large when
+// it exceeds maxBytes, or with 400 Bad Request when it cannot be read. It reports
+// whether the body was read.
+func readBody(gateway *runtime.ServeMux, w http.ResponseWriter, r *http.Request, maxBytes int64) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		_, outbound := runtime.MarshalerForRequest(gateway, r)
+		runtime.HTTPError(r.Context(), gateway, outbound, w, r, &runtime.HTTPStatusError{
+			HTTPStatus: http.StatusRequestEntityTooLarge,
+			Err:        status.Errorf(codes.InvalidArgument, "Request body exceeds %d bytes", tooLarge.Limit),
+		})
+		return nil, false
+	case err != nil:
+		badRequest(gateway, w, r, "Failed to read request body: %v", err)
+		return nil, false
+	}
+	return body, true
+}
+
+// badRequest answers r with an InvalidArgument status, encoded like the errors of
+// the transcoded routes
+func badRequest(gateway *runtime.ServeMux, w http.ResponseWriter, r *http.Request, format string, args ...interface{}) {
+	_, outbound := runtime.MarshalerForRequest(gateway, r)
+	runtime.HTTPError(r.Context(), gateway, outbound, w, r, status.Errorf(codes.InvalidArgument, format, args...))
+}
+
+// forwardSearch serves r as a POST of req to path
+func forwardSearch(gateway *runtime.ServeMux, w http.ResponseWriter, r *http.Request, path string, req *pb.SearchUsersRequest) {
+	body, err := protojson.Marshal(req)
+	if err != nil {
+		_, outbound := runtime.MarshalerForRequest(gateway, r)
+		runtime.HTTPError(r.Context(), gateway, outbound, w, r, status.Errorf(codes.Internal, "Failed to encode search request: %v", err))
+		return
+	}
+	forward(gateway, w, r, http.MethodPost, path, nil, body)
+}
+
+// forward serves r as a request for method and path with query and a JSON body,
+// keeping its headers and context
+func forward(gateway *runtime.ServeMux, w http.ResponseWriter, r *http.Request, method, path string, query url.Values, body []byte) {
+	fwd := r.Clone(r.Context())
+	fwd.Method = method
+	fwd.URL.Path, fwd.URL.RawPath = path, ""
+	fwd.URL.RawQuery = query.Encode()
+	fwd.RequestURI = fwd.URL.RequestURI()
+	if body != nil {
+		fwd.Body = io.NopCloser(bytes.NewReader(body))
+		fwd.ContentLength = int64(len(body))
+		fwd.Header.Set("Content-Type", "application/json")
+	}
+	gateway.ServeHTTP(w, fwd)
+}
+
+// parseSearchRequest accepts either a plain JSON array of criteria or a full
+// SearchUsersRequest object, which is needed to express operators and filter groups,
+// e.g. {"criterias": [...], "filter": {"group": {"logic": "OR", "filters": [...]}}}
+func parseSearchRequest(body []byte) (*pb.SearchUsersRequest, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		// Each criteria in the proto3 JSON mapping, like the object form
+		var elements []json.RawMessage
+		if err := json.Unmarshal(trimmed, &elements); err != nil {
+			return nil, err
+		}
+		req := &pb.SearchUsersRequest{Criterias: make([]*pb.SearchCriteria, len(elements))}
+		for i, element := range elements {
+			req.Criterias[i] = &pb.SearchCriteria{}
+			if err := protojson.Unmarshal(element, req.Criterias[i]); err != nil {
+				return nil, fmt.Errorf("criteria %d: %v", i, err)
+			}
+		}
+		return req, nil
+	}
+
+	req := &pb.SearchUsersRequest{}
+	if err := protojson.Unmarshal(trimmed, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// parsePageParams overrides the paging fields with the page_size, page_token
+// and order_by query parameters when they are present
+func parsePageParams(r *http.Request, pageSize *int32, pageToken, orderBy *string) error {
+	query := r.URL.Query()
+	if v := query.Get("page_size"); v != "" {
+		size, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid page_size %q", v)
+		}
+		*pageSize = int32(size)
+	}
+	if query.Has("page_token") {
+		*pageToken = query.Get("page_token")
+	}
+	if query.Has("order_by") {
+		*orderBy = query.Get("order_by")
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package httpserver
+
+import (
+	"os"
+	"testing"
+
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/logger"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	// The service behind the gateway logs through the global logger, which main initializes
+	logger.SetLogger(zap.NewNop().Sugar())
+	os.Exit(m.Run())
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// apiKeyEntry is one key of the API keys file
+type apiKeyEntry struct {
+	Name  string   `yaml:"name"`  // Subject of the principal, e.g. the calling service
+	Key   string   `yaml:"key"`   // The secret sent by the caller
+	Roles []string `yaml:"roles"` // Roles granted to the key
+}
+
+// apiKeys maps the SHA-256 of each key to its principal, so lookups don't
+// compare secrets byte by byte
+type apiKeys map[[sha256.Size]byte]*Principal
+
+// loadAPIKeys reads a YAML file of the form
+//
+//	keys:
+//	  - name: reporting-job
+//	    key: <secret>
+//	    roles: [viewer]
+func loadAPIKeys(path string) (apiKeys, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading API keys file: %v", err)
+	}
+	var file struct {
+		Keys []apiKeyEntry `yaml:"keys"`
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("error parsing API keys file %s: %v", path, err)
+	}
+
+	keys := make(apiKeys, len(file.Keys))
+	for i, entry := range file.Keys {
+		switch {
+		case entry.Name == "":
+			return nil, fmt.Errorf("API keys file %s: key %d has no name", path, i)
+		case len(entry.Key) < 16:
+			return nil, fmt.Errorf("API keys file %s: key %q must be at least 16 characters", path, entry.Name)
+		}
+		sum := sha256.Sum256([]byte(entry.Key))
+		if _, ok := keys[sum]; ok {
+			return nil, fmt.Errorf("API keys file %s: key %q is a duplicate", path, entry.Name)
+		}
+		keys[sum] = &Principal{Subject: entry.Name, Roles: entry.Roles, Method: MethodAPIKey}
+	}
+	return keys, nil
+}
+
+var errUnknownAPIKey = errors.New("unknown API key")
+
+// lookup returns the principal of key
+func (k apiKeys) lookup(key string) (*Principal, error) {
+	p, ok := k[sha256.Sum256([]byte(key))]
+	if !ok {
+		return nil, errUnknownAPIKey
+	}
+	return p, nil
+}
@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signing algorithms accepted in JWTs
+const (
+	algHS256 = "HS256"
+	algRS256 = "RS256"
+)
+
+// clockSkew is the leeway applied to the exp, nbf and iat claims
+const clockSkew = 30 * time.Second
+
+// JWTOptions configures how JWTs are verified
+type JWTOptions struct {
+	JWKSFile   string // JSON Web Key Set holding the HS256 and RS256 verification keys
+	Issuer     string // Required iss claim, not checked when empty
+	Audience   string // Required aud claim, not checked when empty
+	RolesClaim string // Claim listing the roles of the subject, "roles" when empty
+}
+
+// jwk is a single key of a JWKS file
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	K   string `json:"k"` // Symmetric key, for kty "oct"
+	N   string `json:"n"` // RSA modulus
+	E   string `json:"e"` // RSA exponent
+}
+
+// verificationKey is a parsed JWK
+type verificationKey struct {
+	alg string
+	key interface{} // []byte for HS256, *rsa.PublicKey for RS256
+}
+
+// jwtVerifier validates JWTs against a fixed key set
+type jwtVerifier struct {
+	byKid      map[string]verificationKey
+	keys       []verificationKey
+	parser     *jwt.Parser
+	rolesClaim string
+}
+
+func newJWTVerifier(opts JWTOptions) (*jwtVerifier, error) {
+	v := &jwtVerifier{byKid: make(map[string]verificationKey), rolesClaim: opts.RolesClaim}
+	if v.rolesClaim == "" {
+		v.rolesClaim = "roles"
+	}
+	if err := v.loadJWKS(opts.JWKSFile); err != nil {
+		return nil, err
+	}
+
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{algHS256, algRS256}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+	v.parser = jwt.NewParser(parserOpts...)
+	return v, nil
+}
+
+// loadJWKS reads the signing keys of a JWKS file, skipping encryption keys
+func (v *jwtVerifier) loadJWKS(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading JWKS file: %v", err)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("error parsing JWKS file %s: %v", path, err)
+	}
+
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := parseJWK(k)
+		if err != nil {
+			return fmt.Errorf("JWKS file %s: key %d: %v", path, i, err)
+		}
+		if k.Kid != "" {
+			if _, ok := v.byKid[k.Kid]; ok {
+				return fmt.Errorf("JWKS file %s: duplicate kid %q", path, k.Kid)
+			}
+			v.byKid[k.Kid] = key
+		}
+		v.keys = append(v.keys, key)
+	}
+	if len(v.keys) == 0 {
+		return fmt.Errorf("JWKS file %s has no signing keys", path)
+	}
+	return nil
+}
+
+func parseJWK(k jwk) (verificationKey, error) {
+	switch k.Kty {
+	case "oct":
+		if k.Alg != "" && k.Alg != algHS256 {
+			return verificationKey{}, fmt.Errorf("unsupported algorithm %q for kty oct", k.Alg)
+		}
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return verificationKey{}, fmt.Errorf("invalid k: %v", err)
+		}
+		if len(secret) < 32 {
+			return verificationKey{}, errors.New("HS256 keys must be at least 32 bytes")
+		}
+		return verificationKey{alg: algHS256, key: secret}, nil
+	case "RSA":
+		if k.Alg != "" && k.Alg != algRS256 {
+			return verificationKey{}, fmt.Errorf("unsupported algorithm %q for kty RSA", k.Alg)
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return verificationKey{}, fmt.Errorf("invalid n: %v", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return verificationKey{}, fmt.Errorf("invalid e: %v", err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return verificationKey{}, errors.New("invalid RSA exponent")
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+		if pub.N.BitLen() < 2048 {
+			return verificationKey{}, errors.New("RSA keys must be at least 2048 bits")
+		}
+		return verificationKey{alg: algRS256, key: pub}, nil
+	default:
+		return verificationKey{}, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// keyFunc selects the key verifying a token by its kid, or the only key of the
+// token's algorithm when it has none. The key type must match the algorithm so
+// an RS256 public key can never be used as an HS256 secret.
+func (v *jwtVerifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	alg := token.Method.Alg()
+	if kid, ok := token.Header["kid"].(string); ok && kid != "" {
+		key, ok := v.byKid[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown kid %q", kid)
+		}
+		if key.alg != alg {
+			return nil, fmt.Errorf("kid %q does not sign %s tokens", kid, alg)
+		}
+		return key.key, nil
+	}
+
+	var match *verificationKey
+	for i, key := range v.keys {
+		if key.alg != alg {
+			continue
+		}
+		if match != nil {
+			return nil, errors.New("token has no kid and several keys match its algorithm")
+		}
+		match = &v.keys[i]
+	}
+	if match == nil {
+		return nil, fmt.Errorf("no key for algorithm %s", alg)
+	}
+	return match.key, nil
+}
+
+// verify checks the token signature and claims and returns its principal
+func (v *jwtVerifier) verify(token string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(token, claims, v.keyFunc); err != nil {
+		return nil, err
+	}
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+	roles, err := rolesFromClaim(claims[v.rolesClaim])
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s claim: %v", v.rolesClaim, err)
+	}
+	return &Principal{Subject: subject, Roles: roles, Method: MethodJWT}, nil
+}
+
+// rolesFromClaim accepts a list of strings or a space-separated string, as used by the scope claim
+func rolesFromClaim(claim interface{}) ([]string, error) {
+	switch value := claim.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return strings.Fields(value), nil
+	case []interface{}:
+		roles := make([]string, 0, len(value))
+		for _, r := range value {
+			role, ok := r.(string)
+			if !ok {
+				return nil, errors.New("roles must be strings")
+			}
+			roles = append(roles, role)
+		}
+		return roles, nil
+	default:
+		return nil, errors.New("must be a list of strings or a space-separated string")
+	}
+}
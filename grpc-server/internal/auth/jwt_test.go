@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testKeys are the keys of the JWKS written by writeJWKS
+type testKeys struct {
+	rsa1, rsa2 *rsa.PrivateKey
+	secret     []byte
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+	keys := testKeys{secret: []byte("0123456789abcdef0123456789abcdef")}
+	for _, k := range []**rsa.PrivateKey{&keys.rsa1, &keys.rsa2} {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		*k = key
+	}
+	return keys
+}
+
+// writeJWKS writes the public RSA keys under kids "rsa-1" and "rsa-2" and the
+// secret under "hs", returning the path of the file
+func writeJWKS(t *testing.T, keys testKeys) string {
+	t.Helper()
+	rsaJWK := func(kid string, key *rsa.PrivateKey) jwk {
+		return jwk{
+			Kty: "RSA", Kid: kid, Alg: algRS256, Use: "sig",
+			N: base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}
+	}
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{Keys: []jwk{
+		rsaJWK("rsa-1", keys.rsa1),
+		rsaJWK("rsa-2", keys.rsa2),
+		{Kty: "oct", Kid: "hs", Alg: algHS256, K: base64.RawURLEncoding.EncodeToString(keys.secret)},
+	}}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// sign returns a token of claims signed with key by method, with the given kid
+// header unless it is empty
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("signing token: %v", err)
+	}
+	return signed
+}
+
+func TestJWTVerify(t *testing.T) {
+	keys := newTestKeys(t)
+	verifier, err := newJWTVerifier(JWTOptions{
+		JWKSFile: writeJWKS(t, keys),
+		Issuer:   "https://issuer.example",
+		Audience: "users-api",
+	})
+	if err != nil {
+		t.Fatalf("newJWTVerifier: %v", err)
+	}
+
+	now := time.Now()
+	claims := func(edit func(jwt.MapClaims)) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"sub":   "alice",
+			"iss":   "https://issuer.example",
+			"aud":   "users-api",
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+			"roles": []string{"reader", "admin"},
+		}
+		if edit != nil {
+			edit(c)
+		}
+		return c
+	}
+	// The bytes an attacker would use as HMAC secret, from the published key
+	der, err := x509.MarshalPKIXPublicKey(&keys.rsa1.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"RS256 with kid", sign(t, jwt.SigningMethodRS256, "rsa-2", keys.rsa2, claims(nil)), true},
+		{"HS256 with kid", sign(t, jwt.SigningMethodHS256, "hs", keys.secret, claims(nil)), true},
+		{"HS256 without kid matches the only HS256 key", sign(t, jwt.SigningMethodHS256, "", keys.secret, claims(nil)), true},
+		{"expired within the clock skew", sign(t, jwt.SigningMethodRS256, "rsa-1", keys.rsa1, claims(func(c jwt.MapClaims) {
+			c["exp"] = now.Add(-clockSkew / 2).Unix()
+		})), true},
+
+		// Algorithm confusion
+		{"HS256 signed with the PEM of an RSA key", sign(t, jwt.SigningMethodHS256, "rsa-1", publicPEM, claims(nil)), false},
+		{"HS256 signed with the DER of an RSA key", sign(t, jwt.SigningMethodHS256, "rsa-1", der, claims(nil)), false},
+		{"RS256 with the kid of the HS256 key", sign(t, jwt.SigningMethodRS256, "hs", keys.rsa1, claims(nil)), false},
+		{"alg none", sign(t, jwt.SigningMethodNone, "rsa-1", jwt.UnsafeAllowNoneSignatureType, claims(nil)), false},
+		{"RS384", sign(t, jwt.SigningMethodRS384, "rsa-1", keys.rsa1, claims(nil)), false},
+
+		// Key selection
+		{"unknown kid", sign(t, jwt.SigningMethodRS256, "rsa-3", keys.rsa1, claims(nil)), false},
+		{"kid of another key", sign(t, jwt.SigningMethodRS256, "rsa-2", keys.rsa1, claims(nil)), false},
+		{"missing kid with several RS256 keys", sign(t, jwt.SigningMethodRS256, "", keys.rsa1, claims(nil)), false},
+		{"HS256 with another secret", sign(t, jwt.SigningMethodHS256, "hs", []byte("fedcba9876543210fedcba9876543210"), claims(nil)), false},
+
+		// Claims
+		{"expired", sign(t, jwt.SigningMethodRS256, "rsa-1", keys.rsa1, claims(func(c jwt.MapClaims) {
+			c["exp"] = now.Add(-time.Hour).Unix()
+		})), false},
+		{"no exp", sign(t, jwt.SigningMethodRS256, "rsa-1", keys.rsa1, claims(func(c jwt.MapClaims) { delete(c, "exp") })), false},
+		{"not yet valid", sign(t, jwt.SigningMethodRS256, "rsa-1", keys.rsa1, claims(func(c jwt.MapClaims) {
+			c["nbf"] = now.Add(time.Hour).Unix()
+		})), false},
+		{"wrong iss", sign(t, jwt.SigningMethodRS256, "rsa-1", keys.rsa1, claims(func(c jwt.MapClaims) { c["iss"] = "https://evil.example" })), false},
+		{"no iss", sign(t, jwt.SigningMethodRS256, "rsa-1", keys.rsa1, claims(func(c jwt.MapClaims) { delete(c, "iss") })), false},
+		{"wrong aud", sign(t, jwt.SigningMethodRS256, "rsa-1", keys.rsa1, claims(func(c jwt.MapClaims) { c["aud"] = []string{"other-api"} })), false},
+		{"no aud", sign(t, jwt.SigningMethodRS256, "rsa-1", keys.rsa1, claims(func(c jwt.MapClaims) { delete(c, "aud") })), false},
+		{"no sub", sign(t, jwt.SigningMethodRS256, "rsa-1", keys.rsa1, claims(func(c jwt.MapClaims) { delete(c, "sub") })), false},
+		{"roles of the wrong type", sign(t, jwt.SigningMethodRS256, "rsa-1", keys.rsa1, claims(func(c jwt.MapClaims) { c["roles"] = 1 })), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := verifier.verify(tt.token)
+			if !tt.valid {
+				if err == nil {
+					t.Fatalf("token accepted as %+v", principal)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+			if principal.Subject != "alice" || !slices.Equal(principal.Roles, []string{"reader", "admin"}) || principal.Method != MethodJWT {
+				t.Errorf("principal = %+v", principal)
+			}
+		})
+	}
+}
+
+func TestJWTVerifyWithoutIssuerOrAudience(t *testing.T) {
+	keys := newTestKeys(t)
+	verifier, err := newJWTVerifier(JWTOptions{JWKSFile: writeJWKS(t, keys), RolesClaim: "scope"})
+	if err != nil {
+		t.Fatalf("newJWTVerifier: %v", err)
+	}
+	token := sign(t, jwt.SigningMethodHS256, "hs", keys.secret, jwt.MapClaims{
+		"sub":   "svc",
+		"iss":   "anyone",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scope": "reader writer",
+	})
+	principal, err := verifier.verify(token)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if !slices.Equal(principal.Roles, []string{"reader", "writer"}) {
+		t.Errorf("roles = %v, want those of the scope claim", principal.Roles)
+	}
+}
+
+func TestNewJWTVerifierRejectsWeakKeys(t *testing.T) {
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]jwk{
+		"short secret":    {Kty: "oct", K: base64.RawURLEncoding.EncodeToString([]byte("short"))},
+		"1024-bit RSA":    {Kty: "RSA", N: base64.RawURLEncoding.EncodeToString(weak.N.Bytes()), E: "AQAB"},
+		"HS384 oct key":   {Kty: "oct", Alg: "HS384", K: base64.RawURLEncoding.EncodeToString(make([]byte, 48))},
+		"unsupported kty": {Kty: "EC"},
+	}
+	for name, key := range tests {
+		t.Run(name, func(t *testing.T) {
+			data, _ := json.Marshal(map[string][]jwk{"keys": {key}})
+			path := filepath.Join(t.TempDir(), "jwks.json")
+			if err := os.WriteFile(path, data, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := newJWTVerifier(JWTOptions{JWKSFile: path}); err == nil {
+				t.Error("key accepted")
+			}
+		})
+	}
+}
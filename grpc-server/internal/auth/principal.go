@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"context"
+	"slices"
+)
+
+// Ways a caller can authenticate
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// Principal is the authenticated caller of an RPC
+type Principal struct {
+	Subject string   // API key name or JWT subject
+	Roles   []string // Roles granted to the caller
+	Method  string   // One of the Method* constants
+}
+
+// HasRole reports whether the principal was granted role
+func (p *Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+type principalKey struct{}
+
+// NewContext returns a copy of ctx carrying p
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal stored in ctx by the auth interceptors
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
@@ -0,0 +1,363 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/auth"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/database"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/lifecycle"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/logger"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/ratelimit"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/tlsutil"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/tracing"
+	"google.golang.org/protobuf/encoding/protojson"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the configuration of the gRPC service and its HTTP gateway
+type Config struct {
+	GRPC  GRPCConfig  `yaml:"grpc" toml:"grpc"`
+	HTTP  HTTPConfig  `yaml:"http" toml:"http"`
+	Store StoreConfig `yaml:"store" toml:"store"`
+	Auth  AuthConfig  `yaml:"auth" toml:"auth"`
+
+	RateLimit RateLimitConfig `yaml:"rate_limit" toml:"rate_limit"`
+	Tracing   TracingConfig   `yaml:"tracing" toml:"tracing"`
+	Log       LogConfig       `yaml:"log" toml:"log"`
+	Shutdown  ShutdownConfig  `yaml:"shutdown" toml:"shutdown"`
+}
+
+// GRPCConfig configures the gRPC server
+type GRPCConfig struct {
+	Address string          `yaml:"address" toml:"address"` // host:port the gRPC server listens on
+	TLS     ServerTLSConfig `yaml:"tls" toml:"tls"`
+	// Reflection registers the server reflection service, so tools such as grpcurl
+	// can list and call the services without the proto files
+	Reflection bool `yaml:"reflection" toml:"reflection"`
+}
+
+// ServerTLSConfig enables TLS on a server when CertFile is set. Files are
+// re-read when they change on disk.
+type ServerTLSConfig struct {
+	CertFile     string `yaml:"cert_file" toml:"cert_file"`           // PEM certificate chain
+	KeyFile      string `yaml:"key_file" toml:"key_file"`             // PEM private key of CertFile
+	ClientCAFile string `yaml:"client_ca_file" toml:"client_ca_file"` // CA bundle verifying client certificates, enables mutual TLS
+}
+
+// Enabled reports whether TLS is configured
+func (c ServerTLSConfig) Enabled() bool {
+	return c.CertFile != ""
+}
+
+// Options returns the tlsutil options of the config
+func (c ServerTLSConfig) Options() tlsutil.ServerOptions {
+	return tlsutil.ServerOptions{CertFile: c.CertFile, KeyFile: c.KeyFile, ClientCAFile: c.ClientCAFile}
+}
+
+// HTTPConfig configures the HTTP gateway
+type HTTPConfig struct {
+	Address    string          `yaml:"address" toml:"address"`         // host:port the gateway listens on
+	GRPCTarget string          `yaml:"grpc_target" toml:"grpc_target"` // Address the gateway dials the gRPC server at, in-process when empty
+	GRPCTLS    ClientTLSConfig `yaml:"grpc_tls" toml:"grpc_tls"`       // TLS of the connection to GRPCTarget
+	// MultiplexGRPC also serves gRPC on Address, handing the connections that open
+	// with the HTTP/2 preface to the gRPC server. Only cleartext is supported.
+	MultiplexGRPC bool `yaml:"multiplex_grpc" toml:"multiplex_grpc"`
+	// LogLevelEndpoint serves the log level on /loglevel, changeable with a PUT
+	LogLevelEndpoint bool `yaml:"log_level_endpoint" toml:"log_level_endpoint"`
+	// MaxBodyBytes bounds the request bodies of the original routes, larger ones
+	// are answered with 413 Request Entity Too Large
+	MaxBodyBytes int        `yaml:"max_body_bytes" toml:"max_body_bytes"`
+	JSON         JSONConfig `yaml:"json" toml:"json"`
+}
+
+// JSONConfig configures how the gateway writes messages in JSON
+type JSONConfig struct {
+	EmitUnpopulated bool `yaml:"emit_unpopulated" toml:"emit_unpopulated"` // Write fields holding their zero value, such as married: false
+	UseProtoNames   bool `yaml:"use_proto_names" toml:"use_proto_names"`   // Name fields as in user.proto instead of in lowerCamelCase
+}
+
+// MarshalOptions returns the protojson options of the config
+func (c JSONConfig) MarshalOptions() protojson.MarshalOptions {
+	return protojson.MarshalOptions{EmitUnpopulated: c.EmitUnpopulated, UseProtoNames: c.UseProtoNames}
+}
+
+// ClientTLSConfig configures TLS of an outgoing gRPC connection
+type ClientTLSConfig struct {
+	Enabled    bool   `yaml:"enabled" toml:"enabled"`
+	CAFile     string `yaml:"ca_file" toml:"ca_file"`         // CA bundle verifying the server, the system roots when empty
+	CertFile   string `yaml:"cert_file" toml:"cert_file"`     // Client certificate for mutual TLS
+	KeyFile    string `yaml:"key_file" toml:"key_file"`       // PEM private key of CertFile
+	ServerName string `yaml:"server_name" toml:"server_name"` // Name expected in the server certificate, the dialed host when empty
+}
+
+// Options returns the tlsutil options of the config
+func (c ClientTLSConfig) Options() tlsutil.ClientOptions {
+	return tlsutil.ClientOptions{CAFile: c.CAFile, CertFile: c.CertFile, KeyFile: c.KeyFile, ServerName: c.ServerName}
+}
+
+// StoreConfig configures the user store
+type StoreConfig struct {
+	Backend        string   `yaml:"backend" toml:"backend"`                 // memory or bolt
+	File           string   `yaml:"file" toml:"file"`                       // Users file loaded or seeded from
+	Format         string   `yaml:"format" toml:"format"`                   // Format of File, detected from its extension when empty
+	LoadMode       string   `yaml:"load_mode" toml:"load_mode"`             // strict or lenient
+	BoltPath       string   `yaml:"bolt_path" toml:"bolt_path"`             // bbolt file of the bolt backend
+	ReloadInterval Duration `yaml:"reload_interval" toml:"reload_interval"` // How often File is checked for changes, 0 disables
+}
+
+// AuthConfig enables authentication of RPCs when either credentials file is set
+type AuthConfig struct {
+	APIKeysFile string `yaml:"api_keys_file" toml:"api_keys_file"` // YAML file of API keys with their names and roles
+	JWKSFile    string `yaml:"jwks_file" toml:"jwks_file"`         // JWKS file verifying HS256 and RS256 JWTs
+	JWTIssuer   string `yaml:"jwt_issuer" toml:"jwt_issuer"`       // Required iss claim, not checked when empty
+	JWTAudience string `yaml:"jwt_audience" toml:"jwt_audience"`   // Required aud claim, not checked when empty
+	RolesClaim  string `yaml:"roles_claim" toml:"roles_claim"`     // JWT claim listing the roles of the subject
+	PolicyFile  string `yaml:"policy_file" toml:"policy_file"`     // YAML file of the RPCs and fields each role may access
+}
+
+// Enabled reports whether RPCs must be authenticated
+func (c AuthConfig) Enabled() bool {
+	return c.APIKeysFile != "" || c.JWKSFile != ""
+}
+
+// Options returns the auth options of the config
+func (c AuthConfig) Options() auth.Options {
+	return auth.Options{
+		APIKeysFile: c.APIKeysFile,
+		JWT: auth.JWTOptions{
+			JWKSFile:   c.JWKSFile,
+			Issuer:     c.JWTIssuer,
+			Audience:   c.JWTAudience,
+			RolesClaim: c.RolesClaim,
+		},
+	}
+}
+
+// RateLimitConfig limits the calls per second of each client to the UserService.
+// Clients are identified by their principal when authenticated, by IP otherwise.
+type RateLimitConfig struct {
+	RPS            float64                    `yaml:"rps" toml:"rps"`                         // Default calls per second of every method, 0 disables
+	Burst          int                        `yaml:"burst" toml:"burst"`                     // Default calls allowed at once, the rps rounded up when 0
+	Methods        map[string]ratelimit.Limit `yaml:"methods" toml:"methods"`                 // Limits overriding the default by method name, e.g. SearchUsers
+	TrustedProxies []string                   `yaml:"trusted_proxies" toml:"trusted_proxies"` // IPs or CIDRs whose x-forwarded-for names the client, e.g. the gateway
+}
+
+// Enabled reports whether any method is rate limited
+func (c RateLimitConfig) Enabled() bool {
+	if c.RPS > 0 {
+		return true
+	}
+	for _, limit := range c.Methods {
+		if limit.Enabled() {
+			return true
+		}
+	}
+	return false
+}
+
+// Options returns the rate limiter options of the config
+func (c RateLimitConfig) Options() ratelimit.Options {
+	return ratelimit.Options{
+		Default:        ratelimit.Limit{RPS: c.RPS, Burst: c.Burst},
+		Methods:        c.Methods,
+		TrustedProxies: c.TrustedProxies,
+	}
+}
+
+// TracingConfig selects where OpenTelemetry spans are exported
+type TracingConfig struct {
+	ServiceName  string  `yaml:"service_name" toml:"service_name"`   // service.name of the exported spans
+	Exporter     string  `yaml:"exporter" toml:"exporter"`           // none, otlp or stdout
+	OTLPEndpoint string  `yaml:"otlp_endpoint" toml:"otlp_endpoint"` // host:port of the OTLP/gRPC collector
+	OTLPInsecure bool    `yaml:"otlp_insecure" toml:"otlp_insecure"` // Connect to the collector without TLS
+	File         string  `yaml:"file" toml:"file"`                   // File the stdout exporter appends to, stdout when empty
+	SampleRatio  float64 `yaml:"sample_ratio" toml:"sample_ratio"`   // Fraction of new traces recorded
+}
+
+// Options returns the tracing options of the config
+func (c TracingConfig) Options() tracing.Options {
+	return tracing.Options{
+		ServiceName:  c.ServiceName,
+		Exporter:     c.Exporter,
+		OTLPEndpoint: c.OTLPEndpoint,
+		OTLPInsecure: c.OTLPInsecure,
+		File:         c.File,
+		SampleRatio:  c.SampleRatio,
+	}
+}
+
+// LogConfig configures the service logs
+type LogConfig struct {
+	Level  string `yaml:"level" toml:"level"`   // Minimum level: debug, info, warn or error
+	Format string `yaml:"format" toml:"format"` // json or console
+}
+
+// Options returns the logger options of the config
+func (c LogConfig) Options() logger.Options {
+	return logger.Options{Level: c.Level, Format: c.Format}
+}
+
+// ShutdownConfig configures how the service stops on SIGINT or SIGTERM
+type ShutdownConfig struct {
+	PreStopDelay Duration `yaml:"pre_stop_delay" toml:"pre_stop_delay"` // How long to keep serving while reporting NOT_SERVING
+	DrainTimeout Duration `yaml:"drain_timeout" toml:"drain_timeout"`   // Deadline for in-flight calls, after which servers are stopped
+}
+
+// Options returns the lifecycle options of the config
+func (c ShutdownConfig) Options() lifecycle.Options {
+	return lifecycle.Options{PreStopDelay: time.Duration(c.PreStopDelay), DrainTimeout: time.Duration(c.DrainTimeout)}
+}
+
+// Default returns the configuration used when nothing is overridden
+func Default() *Config {
+	return &Config{
+		GRPC: GRPCConfig{Address: ":50051"},
+		HTTP: HTTPConfig{Address: ":8082", MaxBodyBytes: 1 << 20, JSON: JSONConfig{EmitUnpopulated: true}},
+		Store: StoreConfig{
+			Backend:  database.BackendMemory,
+			File:     "internal/utils/simulated_entry.json",
+			LoadMode: database.LoadStrict,
+			BoltPath: "data/users.db",
+		},
+		Auth:     AuthConfig{RolesClaim: "roles"},
+		Tracing:  TracingConfig{ServiceName: "grpc-service", Exporter: tracing.ExporterNone, SampleRatio: 1},
+		Log:      LogConfig{Level: "info", Format: logger.FormatJSON},
+		Shutdown: ShutdownConfig{DrainTimeout: Duration(20 * time.Second)},
+	}
+}
+
+// Validate reports every invalid setting
+func (c *Config) Validate() error {
+	var problems []string
+	check := func(key string, err error) {
+		if err != nil {
+			problems = append(problems, key+": "+err.Error())
+		}
+	}
+	// gRPC may be served on the HTTP address only
+	if c.GRPC.Address != "" || !c.HTTP.MultiplexGRPC {
+		check("grpc.address", validateAddress(c.GRPC.Address))
+	}
+	check("http.address", validateAddress(c.HTTP.Address))
+	if c.HTTP.GRPCTarget != "" {
+		check("http.grpc_target", validateAddress(c.HTTP.GRPCTarget))
+	}
+	if c.HTTP.MaxBodyBytes <= 0 {
+		check("http.max_body_bytes", errors.New("must be positive"))
+	}
+	if (c.GRPC.TLS.CertFile == "") != (c.GRPC.TLS.KeyFile == "") {
+		check("grpc.tls", errors.New("cert_file and key_file must be set together"))
+	}
+	if c.GRPC.TLS.ClientCAFile != "" && !c.GRPC.TLS.Enabled() {
+		check("grpc.tls.client_ca_file", errors.New("requires cert_file and key_file"))
+	}
+	if (c.HTTP.GRPCTLS.CertFile == "") != (c.HTTP.GRPCTLS.KeyFile == "") {
+		check("http.grpc_tls", errors.New("cert_file and key_file must be set together"))
+	}
+	// The in-process connection of the gateway needs no TLS
+	if c.GRPC.TLS.Enabled() && !c.HTTP.GRPCTLS.Enabled && c.HTTP.GRPCTarget != "" {
+		check("http.grpc_tls.enabled", errors.New("must be true when grpc.tls is configured"))
+	}
+	if c.GRPC.TLS.ClientCAFile != "" && c.HTTP.GRPCTLS.CertFile == "" && c.HTTP.GRPCTarget != "" {
+		check("http.grpc_tls.cert_file", errors.New("is required when grpc.tls.client_ca_file enables mutual TLS"))
+	}
+	// The connections are split before any handshake, which TLS would hide
+	if c.HTTP.MultiplexGRPC && c.GRPC.TLS.Enabled() {
+		check("http.multiplex_grpc", errors.New("cannot be combined with grpc.tls, serve TLS on grpc.address"))
+	}
+	check("store.backend", oneOf(c.Store.Backend, database.BackendMemory, database.BackendBolt))
+	if c.Store.File == "" && c.Store.Backend == database.BackendMemory {
+		check("store.file", errors.New("is required by the memory backend"))
+	}
+	if c.Store.Format != "" {
+		check("store.format", oneOf(c.Store.Format, database.FormatJSON, database.FormatNDJSON, database.FormatCSV, database.FormatYAML))
+	}
+	check("store.load_mode", oneOf(c.Store.LoadMode, database.LoadStrict, database.LoadLenient))
+	if c.Store.BoltPath == "" && c.Store.Backend == database.BackendBolt {
+		check("store.bolt_path", errors.New("is required by the bolt backend"))
+	}
+	if c.Store.ReloadInterval < 0 {
+		check("store.reload_interval", errors.New("must not be negative"))
+	}
+	if c.RateLimit.RPS < 0 {
+		check("rate_limit.rps", errors.New("must not be negative"))
+	}
+	if c.RateLimit.Burst < 0 {
+		check("rate_limit.burst", errors.New("must not be negative"))
+	}
+	check("log.level", oneOf(c.Log.Level, "debug", "info", "warn", "error"))
+	check("log.format", oneOf(c.Log.Format, logger.FormatJSON, logger.FormatConsole))
+	check("tracing.exporter", oneOf(c.Tracing.Exporter, tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout))
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		check("tracing.sample_ratio", errors.New("must be between 0 and 1"))
+	}
+	if c.Shutdown.PreStopDelay < 0 {
+		check("shutdown.pre_stop_delay", errors.New("must not be negative"))
+	}
+	if c.Shutdown.DrainTimeout <= 0 {
+		check("shutdown.drain_timeout", errors.New("must be positive"))
+	}
+	if c.Auth.PolicyFile != "" && !c.Auth.Enabled() {
+		check("auth.policy_file", errors.New("requires auth.api_keys_file or auth.jwks_file"))
+	}
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Print writes the configuration as YAML, in the layout of a config file
+func (c *Config) Print(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// Duration is a time.Duration written as a string such as "5s" in files, flags and env
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// Set parses a duration, making Duration usable as a flag.Value
+func (d *Duration) Set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	return d.Set(string(text))
+}
+
+func validateAddress(address string) error {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return fmt.Errorf("must be host:port, got %q", address)
+	}
+	return nil
+}
+
+func oneOf(value string, allowed ...string) error {
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of %q, got %q", allowed, value)
+}
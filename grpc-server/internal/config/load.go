@@ -0,0 +1,250 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileEnv names the environment variable selecting the config file
+const ConfigFileEnv = "CONFIG_FILE"
+
+// setting binds a Config field to its environment variable and flag
+type setting struct {
+	key   string     // Dotted path of the field in the config file, e.g. "grpc.address"
+	env   string     // Environment variable overriding the file
+	usage string     // Flag help text
+	value flag.Value // Field the setting writes to
+}
+
+// flagName derives the flag of a setting from its key, e.g. "store.bolt_path" becomes "store-bolt-path"
+func (s setting) flagName() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(s.key)
+}
+
+// settings lists every setting of c that can be overridden
+func (c *Config) settings() []setting {
+	return []setting{
+		{"grpc.address", "GRPC_ADDRESS", "address the gRPC server listens on", (*stringValue)(&c.GRPC.Address)},
+		{"http.address", "HTTP_ADDRESS", "address the HTTP gateway listens on", (*stringValue)(&c.HTTP.Address)},
+		{"grpc.tls.cert_file", "GRPC_TLS_CERT_FILE", "PEM certificate of the gRPC server, enables TLS", (*stringValue)(&c.GRPC.TLS.CertFile)},
+		{"grpc.tls.key_file", "GRPC_TLS_KEY_FILE", "PEM private key of the gRPC server certificate", (*stringValue)(&c.GRPC.TLS.KeyFile)},
+		{"grpc.tls.client_ca_file", "GRPC_TLS_CLIENT_CA_FILE", "CA bundle verifying client certificates, enables mutual TLS", (*stringValue)(&c.GRPC.TLS.ClientCAFile)},
+		{"grpc.reflection", "GRPC_REFLECTION", "register the gRPC server reflection service", (*boolValue)(&c.GRPC.Reflection)},
+		{"http.grpc_target", "HTTP_GRPC_TARGET", "address the HTTP gateway dials the gRPC server at, in-process when empty", (*stringValue)(&c.HTTP.GRPCTarget)},
+		{"http.multiplex_grpc", "HTTP_MULTIPLEX_GRPC", "also serve gRPC on the HTTP address; grpc.address may then be empty", (*boolValue)(&c.HTTP.MultiplexGRPC)},
+		{"http.grpc_tls.enabled", "HTTP_GRPC_TLS_ENABLED", "dial the gRPC server over TLS", (*boolValue)(&c.HTTP.GRPCTLS.Enabled)},
+		{"http.grpc_tls.ca_file", "HTTP_GRPC_TLS_CA_FILE", "CA bundle verifying the gRPC server, the system roots when empty", (*stringValue)(&c.HTTP.GRPCTLS.CAFile)},
+		{"http.grpc_tls.cert_file", "HTTP_GRPC_TLS_CERT_FILE", "client certificate the gateway presents for mutual TLS", (*stringValue)(&c.HTTP.GRPCTLS.CertFile)},
+		{"http.grpc_tls.key_file", "HTTP_GRPC_TLS_KEY_FILE", "PEM private key of the gateway client certificate", (*stringValue)(&c.HTTP.GRPCTLS.KeyFile)},
+		{"http.grpc_tls.server_name", "HTTP_GRPC_TLS_SERVER_NAME", "name expected in the gRPC server certificate", (*stringValue)(&c.HTTP.GRPCTLS.ServerName)},
+		{"http.log_level_endpoint", "HTTP_LOG_LEVEL_ENDPOINT", "serve the log level on /loglevel of the gateway, changeable with a PUT", (*boolValue)(&c.HTTP.LogLevelEndpoint)},
+		{"http.max_body_bytes", "HTTP_MAX_BODY_BYTES", "largest request body of the original REST routes, in bytes", (*intValue)(&c.HTTP.MaxBodyBytes)},
+		{"http.json.emit_unpopulated", "HTTP_JSON_EMIT_UNPOPULATED", "write fields holding their zero value in JSON responses", (*boolValue)(&c.HTTP.JSON.EmitUnpopulated)},
+		{"http.json.use_proto_names", "HTTP_JSON_USE_PROTO_NAMES", "name fields of JSON responses as in user.proto instead of in lowerCamelCase", (*boolValue)(&c.HTTP.JSON.UseProtoNames)},
+		{"log.level", "LOG_LEVEL", "minimum log level: debug, info, warn or error", (*stringValue)(&c.Log.Level)},
+		{"log.format", "LOG_FORMAT", "log format: json or console", (*stringValue)(&c.Log.Format)},
+		{"store.backend", "STORE_BACKEND", "storage backend: memory or bolt", (*stringValue)(&c.Store.Backend)},
+		{"store.file", "JSON_FILE_PATH", "users file loaded by the memory backend or seeding the bolt backend", (*stringValue)(&c.Store.File)},
+		{"store.format", "JSON_FILE_FORMAT", "format of the users file: json, ndjson, csv or yaml (default detected from the extension)", (*stringValue)(&c.Store.Format)},
+		{"store.load_mode", "JSON_LOAD_MODE", "how invalid records are handled: strict or lenient", (*stringValue)(&c.Store.LoadMode)},
+		{"store.bolt_path", "BOLT_DB_PATH", "bbolt file of the bolt backend", (*stringValue)(&c.Store.BoltPath)},
+		{"store.reload_interval", "JSON_RELOAD_INTERVAL", "how often the users file is checked for changes, 0 disables", &c.Store.ReloadInterval},
+		{"auth.api_keys_file", "AUTH_API_KEYS_FILE", "YAML file of API keys, enables authentication", (*stringValue)(&c.Auth.APIKeysFile)},
+		{"auth.jwks_file", "AUTH_JWKS_FILE", "JWKS file verifying HS256 and RS256 JWTs, enables authentication", (*stringValue)(&c.Auth.JWKSFile)},
+		{"auth.jwt_issuer", "AUTH_JWT_ISSUER", "required iss claim of JWTs", (*stringValue)(&c.Auth.JWTIssuer)},
+		{"auth.jwt_audience", "AUTH_JWT_AUDIENCE", "required aud claim of JWTs", (*stringValue)(&c.Auth.JWTAudience)},
+		{"auth.roles_claim", "AUTH_ROLES_CLAIM", "JWT claim listing the roles of the subject", (*stringValue)(&c.Auth.RolesClaim)},
+		{"auth.policy_file", "AUTH_POLICY_FILE", "YAML file of the RPCs and user fields each role may access", (*stringValue)(&c.Auth.PolicyFile)},
+		{"rate_limit.rps", "RATE_LIMIT_RPS", "calls per second each client may make to every method, 0 disables", (*floatValue)(&c.RateLimit.RPS)},
+		{"rate_limit.burst", "RATE_LIMIT_BURST", "calls each client may make at once, the rps rounded up when 0", (*intValue)(&c.RateLimit.Burst)},
+		{"rate_limit.trusted_proxies", "RATE_LIMIT_TRUSTED_PROXIES", "comma-separated IPs or CIDRs whose x-forwarded-for names the client", (*listValue)(&c.RateLimit.TrustedProxies)},
+		{"shutdown.pre_stop_delay", "SHUTDOWN_PRE_STOP_DELAY", "how long to keep serving while health checks report NOT_SERVING on shutdown", &c.Shutdown.PreStopDelay},
+		{"shutdown.drain_timeout", "SHUTDOWN_DRAIN_TIMEOUT", "deadline for in-flight calls on shutdown, after which the servers are stopped", &c.Shutdown.DrainTimeout},
+		{"tracing.service_name", "TRACING_SERVICE_NAME", "service.name of the exported spans", (*stringValue)(&c.Tracing.ServiceName)},
+		{"tracing.exporter", "TRACING_EXPORTER", "span exporter: none, otlp or stdout", (*stringValue)(&c.Tracing.Exporter)},
+		{"tracing.otlp_endpoint", "TRACING_OTLP_ENDPOINT", "host:port of the OTLP/gRPC collector (default from OTEL_EXPORTER_OTLP_ENDPOINT)", (*stringValue)(&c.Tracing.OTLPEndpoint)},
+		{"tracing.otlp_insecure", "TRACING_OTLP_INSECURE", "connect to the OTLP collector without TLS", (*boolValue)(&c.Tracing.OTLPInsecure)},
+		{"tracing.file", "TRACING_FILE", "file the stdout exporter appends spans to, stdout when empty", (*stringValue)(&c.Tracing.File)},
+		{"tracing.sample_ratio", "TRACING_SAMPLE_RATIO", "fraction of new traces recorded, between 0 and 1", (*floatValue)(&c.Tracing.SampleRatio)},
+	}
+}
+
+// Load builds the configuration from, in increasing order of precedence, the
+// defaults, the YAML or TOML file named by -config or CONFIG_FILE, environment
+// variables and the flags in args, then validates it. printOnly reports whether
+// -print-config was given, in which case the caller should print and exit.
+func Load(name string, args []string) (cfg *Config, printOnly bool, err error) {
+	cfg = Default()
+	settings := cfg.settings()
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv(ConfigFileEnv), "YAML or TOML config file (env "+ConfigFileEnv+")")
+	printConfig := fs.Bool("print-config", false, "print the effective configuration and exit")
+	// Flags are parsed into placeholders first, so they can be applied after the file and env
+	flags := make(map[string]*pendingFlag, len(settings))
+	byFlag := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		usage := fmt.Sprintf("%s (env %s, default %q)", s.usage, s.env, s.value.String())
+		_, boolean := s.value.(*boolValue)
+		flags[s.flagName()] = &pendingFlag{boolean: boolean}
+		fs.Var(flags[s.flagName()], s.flagName(), usage)
+		byFlag[s.flagName()] = s
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, false, err
+	}
+	if fs.NArg() > 0 {
+		return nil, false, fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, false, err
+		}
+	}
+	for _, s := range settings {
+		if v := os.Getenv(s.env); v != "" {
+			if err := s.value.Set(v); err != nil {
+				return nil, false, fmt.Errorf("invalid %s %q: %v", s.env, v, err)
+			}
+		}
+	}
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		s, ok := byFlag[f.Name]
+		if !ok || flagErr != nil {
+			return
+		}
+		if err := s.value.Set(flags[f.Name].value); err != nil {
+			flagErr = fmt.Errorf("invalid value %q for flag -%s: %v", flags[f.Name].value, f.Name, err)
+		}
+	})
+	if flagErr != nil {
+		return nil, false, flagErr
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, false, err
+	}
+	return cfg, *printConfig, nil
+}
+
+// loadFile overlays the settings of a YAML or TOML file, rejecting unknown keys
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %v", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("error parsing config file %s: %v", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), c)
+		if err != nil {
+			return fmt.Errorf("error parsing config file %s: %v", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("error parsing config file %s: unknown keys %v", path, undecoded)
+		}
+	default:
+		return fmt.Errorf("config file %s must have a .yaml, .yml or .toml extension", path)
+	}
+	return nil
+}
+
+// stringValue is a string field usable as a flag.Value
+type stringValue string
+
+func (s *stringValue) String() string { return string(*s) }
+
+func (s *stringValue) Set(v string) error {
+	*s = stringValue(v)
+	return nil
+}
+
+// boolValue is a bool field usable as a flag.Value
+type boolValue bool
+
+func (b *boolValue) String() string { return strconv.FormatBool(bool(*b)) }
+
+func (b *boolValue) Set(v string) error {
+	parsed, err := strconv.ParseBool(v)
+	if err != nil {
+		return err
+	}
+	*b = boolValue(parsed)
+	return nil
+}
+
+// intValue is an int field usable as a flag.Value
+type intValue int
+
+func (i *intValue) String() string { return strconv.Itoa(int(*i)) }
+
+func (i *intValue) Set(v string) error {
+	parsed, err := strconv.Atoi(v)
+	if err != nil {
+		return err
+	}
+	*i = intValue(parsed)
+	return nil
+}
+
+// floatValue is a float64 field usable as a flag.Value
+type floatValue float64
+
+func (f *floatValue) String() string { return strconv.FormatFloat(float64(*f), 'g', -1, 64) }
+
+func (f *floatValue) Set(v string) error {
+	parsed, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return err
+	}
+	*f = floatValue(parsed)
+	return nil
+}
+
+// listValue is a comma-separated string list usable as a flag.Value
+type listValue []string
+
+func (l *listValue) String() string { return strings.Join(*l, ",") }
+
+func (l *listValue) Set(v string) error {
+	*l = nil
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// pendingFlag holds a flag value until it is applied on top of the file and env
+type pendingFlag struct {
+	value   string
+	boolean bool
+}
+
+func (p *pendingFlag) String() string { return p.value }
+
+func (p *pendingFlag) Set(v string) error {
+	p.value = v
+	return nil
+}
+
+// IsBoolFlag lets boolean settings be given as a bare -flag
+func (p *pendingFlag) IsBoolFlag() bool { return p.boolean }
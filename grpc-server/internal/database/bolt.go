@@ -0,0 +1,498 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/logger"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	usersBucket      = []byte("users")
+	metaBucket       = []byte("meta")
+	schemaVersionKey = []byte("schema_version")
+	seededKey        = []byte("seeded") // Set once the store was seeded, even when nothing was imported
+)
+
+// migration upgrades the bolt file to the given schema version
+type migration struct {
+	version     uint64
+	description string
+	apply       func(tx *bolt.Tx) error
+}
+
+// migrations are applied in order on startup; append new entries, never edit old ones
+var migrations = []migration{
+	{
+		version:     1,
+		description: "create users bucket",
+		apply: func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(usersBucket)
+			return err
+		},
+	},
+}
+
+// BoltStore is a durable UserStore persisting users in a bbolt file
+type BoltStore struct {
+	db *bolt.DB
+}
+
+var _ UserStore = (*BoltStore)(nil)
+
+// NewBoltStore opens the bolt file at dbPath, applies pending migrations and,
+// the first time it is opened with a seedPath, seeds it from that users file.
+// Deleting every user later does not import the file again.
+func NewBoltStore(dbPath, seedPath string, opts LoadOptions) (*BoltStore, error) {
+	logger.Infof("Opening bolt store at %s", dbPath)
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
+		return nil, fmt.Errorf("error creating bolt directory: %v", err)
+	}
+	db, err := bolt.Open(dbPath, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		logger.Errorf("Failed to open bolt file %s: %v", dbPath, err)
+		return nil, fmt.Errorf("error opening bolt file: %v", err)
+	}
+	store := &BoltStore{db: db}
+
+	if err := store.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if seedPath != "" {
+		if err := store.seed(seedPath, opts); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	logger.Info("Bolt store initialization complete")
+	return store, nil
+}
+
+// migrate applies every migration newer than the stored schema version
+func (s *BoltStore) migrate() error {
+	return s.update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+		var current uint64
+		if v := meta.Get(schemaVersionKey); v != nil {
+			current = binary.BigEndian.Uint64(v)
+		}
+		for _, m := range migrations {
+			if m.version <= current {
+				continue
+			}
+			logger.Infof("Applying bolt migration %v: %s", m.version, m.description)
+			if err := m.apply(tx); err != nil {
+				return fmt.Errorf("error applying migration %v: %v", m.version, err)
+			}
+			current = m.version
+		}
+		return meta.Put(schemaVersionKey, binary.BigEndian.AppendUint64(nil, current))
+	})
+}
+
+// seed imports the users file at path unless the store was seeded before. Stores
+// created before seeding was recorded count as seeded when they hold users.
+func (s *BoltStore) seed(path string, opts LoadOptions) error {
+	var seeded bool
+	err := s.view(func(tx *bolt.Tx) error {
+		seeded = tx.Bucket(metaBucket).Get(seededKey) != nil
+		return nil
+	})
+	if err != nil || seeded {
+		return err
+	}
+	empty, err := s.empty()
+	if err != nil {
+		return err
+	}
+	if empty {
+		n, err := s.ImportFile(context.Background(), path, opts)
+		if err != nil {
+			return err
+		}
+		logger.Infof("Seeded bolt store with %v users from %s", n, path)
+	}
+	return s.update(func(tx *bolt.Tx) error {
+		return tx.Bucket(metaBucket).Put(seededKey, []byte(path))
+	})
+}
+
+// empty reports whether the users bucket holds no records
+func (s *BoltStore) empty() (bool, error) {
+	var empty bool
+	err := s.view(func(tx *bolt.Tx) error {
+		k, _ := tx.Bucket(usersBucket).Cursor().First()
+		empty = k == nil
+		return nil
+	})
+	return empty, err
+}
+
+// ImportFile upserts every user from the users file at path, in any supported format
+func (s *BoltStore) ImportFile(ctx context.Context, path string, opts LoadOptions) (int, error) {
+	users, err := loadUsers(path, opts)
+	if err != nil {
+		return 0, err
+	}
+	return s.Import(ctx, users)
+}
+
+// Import upserts users in a single transaction and returns how many were written
+func (s *BoltStore) Import(ctx context.Context, users []*pb.User) (int, error) {
+	err := s.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(usersBucket)
+		for _, user := range users {
+			if err := putUser(b, user); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to import users: %v", err)
+		return 0, err
+	}
+	return len(users), nil
+}
+
+// GetUserByID retrieves a user by ID from the bolt file
+func (s *BoltStore) GetUserByID(ctx context.Context, id int32) (*pb.User, error) {
+	logger.FromContext(ctx).Debugf("Fetching user with ID %v", id)
+	var user *pb.User
+	err := s.view(func(tx *bolt.Tx) error {
+		var err error
+		user, err = getUser(tx.Bucket(usersBucket), id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		logger.FromContext(ctx).Warnf("User with ID %v not found", id)
+		return nil, ErrUserNotFound
+	}
+	logger.FromContext(ctx).Infof("User with ID %v found", id)
+	return user, nil
+}
+
+// GetUsersByID retrieves a list of users by IDs from the bolt file
+func (s *BoltStore) GetUsersByID(ctx context.Context, ids []int32) ([]*pb.User, error) {
+	var users []*pb.User
+	err := s.view(func(tx *bolt.Tx) error {
+		b := tx.Bucket(usersBucket)
+		for _, id := range ids {
+			user, err := getUser(b, id)
+			if err != nil {
+				return err
+			}
+			if user == nil {
+				logger.FromContext(ctx).Warnf("User with ID %v not found", id)
+				continue
+			}
+			users = append(users, user)
+			logger.FromContext(ctx).Debugf("User with ID %v added to result", id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).Infof("Retrieved %v users by IDs", len(users))
+	return users, nil
+}
+
+// SearchUsers scans the bolt file for users matching the query
+func (s *BoltStore) SearchUsers(ctx context.Context, q Query) ([]*pb.User, error) {
+	return traceSearch(ctx, "BoltStore.SearchUsers", BackendBolt, q, func() ([]*pb.User, error) {
+		return s.searchUsers(ctx, q)
+	})
+}
+
+func (s *BoltStore) searchUsers(ctx context.Context, q Query) ([]*pb.User, error) {
+	match, err := compileQuery(q)
+	if err != nil {
+		logger.FromContext(ctx).Warnf("Rejected search query: %v", err)
+		return nil, err
+	}
+	var users []*pb.User
+	err = s.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(usersBucket).ForEach(func(k, v []byte) error {
+			user := &pb.User{}
+			if err := proto.Unmarshal(v, user); err != nil {
+				return fmt.Errorf("error decoding user %v: %v", keyID(k), err)
+			}
+			if match(user) {
+				users = append(users, user)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		logger.FromContext(ctx).Warn("No users found matching the criteria")
+		return nil, ErrNoUsersFound
+	}
+	logger.FromContext(ctx).Infof("Found %v users matching the criteria", len(users))
+	return users, nil
+}
+
+// ScanUsers streams the users matching the query to fn in ID order
+func (s *BoltStore) ScanUsers(ctx context.Context, q Query, fn func(*pb.User) error) error {
+	return traceScan(ctx, "BoltStore.ScanUsers", BackendBolt, q, fn, func(ctx context.Context, fn func(*pb.User) error) error {
+		return s.scanUsers(ctx, q, fn)
+	})
+}
+
+func (s *BoltStore) scanUsers(ctx context.Context, q Query, fn func(*pb.User) error) error {
+	match, err := compileQuery(q)
+	if err != nil {
+		logger.FromContext(ctx).Warnf("Rejected search query: %v", err)
+		return err
+	}
+	// The read transaction is closed before fn is called, so a slow client cannot
+	// keep it open and stop bolt from reusing the pages freed by writes
+	var after []byte
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		batch, last, err := s.scanBatch(after, match)
+		if err != nil {
+			return err
+		}
+		for _, user := range batch {
+			if err := fn(user); err != nil {
+				return err
+			}
+		}
+		if last == nil {
+			return nil
+		}
+		after = last
+	}
+}
+
+// scanBatchSize bounds the matching users decoded in one read transaction
+const scanBatchSize = 256
+
+// scanBatch decodes up to scanBatchSize users matching match, starting after the
+// key after or at the first key when it is nil. It returns the key of the last
+// user it read, or nil once the bucket is exhausted.
+func (s *BoltStore) scanBatch(after []byte, match func(*pb.User) bool) ([]*pb.User, []byte, error) {
+	var batch []*pb.User
+	var last []byte
+	err := s.view(func(tx *bolt.Tx) error {
+		c := tx.Bucket(usersBucket).Cursor()
+		k, v := c.First()
+		if after != nil {
+			k, v = c.Seek(after)
+			if bytes.Equal(k, after) {
+				k, v = c.Next()
+			}
+		}
+		for ; k != nil; k, v = c.Next() {
+			user := &pb.User{}
+			if err := proto.Unmarshal(v, user); err != nil {
+				return fmt.Errorf("error decoding user %v: %v", keyID(k), err)
+			}
+			if !match(user) {
+				continue
+			}
+			batch = append(batch, user)
+			if len(batch) == scanBatchSize {
+				// Keys are only valid during the transaction
+				last = append([]byte(nil), k...)
+				return nil
+			}
+		}
+		return nil
+	})
+	return batch, last, err
+}
+
+// CreateUser stores a new user, assigning the next free ID when user.Id is zero
+func (s *BoltStore) CreateUser(ctx context.Context, user *pb.User) (*pb.User, error) {
+	if user == nil {
+		return nil, invalidArgument("user", "user is required")
+	}
+	created := proto.Clone(user).(*pb.User)
+	if created.Id < 0 {
+		logger.FromContext(ctx).Warnf("Rejected user with invalid ID %v", created.Id)
+		return nil, invalidArgument("user.id", "must be greater than 0")
+	}
+	err := s.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(usersBucket)
+		if created.Id == 0 {
+			id, err := nextFreeID(b)
+			if err != nil {
+				return err
+			}
+			created.Id = id
+		}
+		if b.Get(idKey(created.Id)) != nil {
+			logger.FromContext(ctx).Warnf("User with ID %v already exists", created.Id)
+			return ErrUserExists
+		}
+		return putUser(b, created)
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).Infof("User with ID %v created", created.Id)
+	return created, nil
+}
+
+// UpdateUser overwrites the fields named in paths with the values from user.
+// An empty paths slice replaces every field except the ID.
+func (s *BoltStore) UpdateUser(ctx context.Context, user *pb.User, paths []string) (*pb.User, error) {
+	if user == nil {
+		return nil, invalidArgument("user", "user is required")
+	}
+	if len(paths) == 0 {
+		paths = allFields
+	}
+	var updated *pb.User
+	err := s.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(usersBucket)
+		existing, err := getUser(b, user.Id)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			logger.FromContext(ctx).Warnf("User with ID %v not found", user.Id)
+			return ErrUserNotFound
+		}
+		for _, path := range paths {
+			if err := applyField(existing, user, path); err != nil {
+				logger.FromContext(ctx).Warnf("Rejected update of user with ID %v: %v", user.Id, err)
+				return err
+			}
+		}
+		updated = existing
+		return putUser(b, updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).Infof("User with ID %v updated", updated.Id)
+	return updated, nil
+}
+
+// DeleteUser removes a user by ID from the bolt file
+func (s *BoltStore) DeleteUser(ctx context.Context, id int32) error {
+	err := s.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(usersBucket)
+		if b.Get(idKey(id)) == nil {
+			logger.FromContext(ctx).Warnf("User with ID %v not found", id)
+			return ErrUserNotFound
+		}
+		return b.Delete(idKey(id))
+	})
+	if err != nil {
+		return err
+	}
+	logger.FromContext(ctx).Infof("User with ID %v deleted", id)
+	return nil
+}
+
+// CountUsers returns the number of users in the bolt file
+func (s *BoltStore) CountUsers(ctx context.Context) (int, error) {
+	var n int
+	err := s.view(func(tx *bolt.Tx) error {
+		n = tx.Bucket(usersBucket).Stats().KeyN
+		return nil
+	})
+	return n, err
+}
+
+// Close closes the underlying bolt file
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// view runs fn in a read-only transaction
+func (s *BoltStore) view(fn func(tx *bolt.Tx) error) error {
+	return storeErr(s.db.View(fn))
+}
+
+// update runs fn in a read-write transaction
+func (s *BoltStore) update(fn func(tx *bolt.Tx) error) error {
+	return storeErr(s.db.Update(fn))
+}
+
+// storeErr reports a closed bolt file as ErrStoreUnavailable
+func storeErr(err error) error {
+	if errors.Is(err, bolt.ErrDatabaseNotOpen) {
+		return fmt.Errorf("%w: %v", ErrStoreUnavailable, err)
+	}
+	return err
+}
+
+// idKey encodes a user ID as a big-endian key so the cursor iterates in ID order
+func idKey(id int32) []byte {
+	return binary.BigEndian.AppendUint32(nil, uint32(id))
+}
+
+// keyID decodes a key produced by idKey
+func keyID(k []byte) int32 {
+	return int32(binary.BigEndian.Uint32(k))
+}
+
+// getUser decodes the user stored under id, returning nil when it does not exist
+func getUser(b *bolt.Bucket, id int32) (*pb.User, error) {
+	v := b.Get(idKey(id))
+	if v == nil {
+		return nil, nil
+	}
+	user := &pb.User{}
+	if err := proto.Unmarshal(v, user); err != nil {
+		return nil, fmt.Errorf("error decoding user %v: %v", id, err)
+	}
+	return user, nil
+}
+
+// putUser encodes and stores user, keeping the bucket sequence above every stored ID
+func putUser(b *bolt.Bucket, user *pb.User) error {
+	if user.Id <= 0 {
+		return fmt.Errorf("user ID must be greater than 0, got %v", user.Id)
+	}
+	v, err := proto.Marshal(user)
+	if err != nil {
+		return fmt.Errorf("error encoding user %v: %v", user.Id, err)
+	}
+	if uint64(user.Id) > b.Sequence() {
+		if err := b.SetSequence(uint64(user.Id)); err != nil {
+			return err
+		}
+	}
+	return b.Put(idKey(user.Id), v)
+}
+
+// nextFreeID returns the next ID after the highest one ever stored
+func nextFreeID(b *bolt.Bucket) (int32, error) {
+	seq, err := b.NextSequence()
+	if err != nil {
+		return 0, err
+	}
+	if seq > uint64(^uint32(0)>>1) {
+		return 0, errors.New("user ID space exhausted")
+	}
+	return int32(seq), nil
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
+)
+
+const seedUsers = `[
+  {"id": 1, "fname": "John", "city": "New York", "phone": 1234567890, "height": 180.5, "married": true},
+  {"id": 2, "fname": "Jane", "city": "Los Angeles", "phone": 2345678901, "height": 165.3, "married": false}
+]`
+
+func TestNewBoltStoreSeedsOnce(t *testing.T) {
+	dir := t.TempDir()
+	seedPath := filepath.Join(dir, "users.json")
+	if err := os.WriteFile(seedPath, []byte(seedUsers), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dbPath := filepath.Join(dir, "users.db")
+	ctx := context.Background()
+
+	store, err := NewBoltStore(dbPath, seedPath, LoadOptions{})
+	if err != nil {
+		t.Fatalf("NewBoltStore: %v", err)
+	}
+	if n, _ := store.CountUsers(ctx); n != 2 {
+		t.Fatalf("seeded %d users, want 2", n)
+	}
+	for _, id := range []int32{1, 2} {
+		if err := store.DeleteUser(ctx, id); err != nil {
+			t.Fatalf("DeleteUser(%d): %v", id, err)
+		}
+	}
+	store.Close()
+
+	// Deleting every user must not bring the seed back on restart
+	store, err = NewBoltStore(dbPath, seedPath, LoadOptions{})
+	if err != nil {
+		t.Fatalf("reopening: %v", err)
+	}
+	defer store.Close()
+	if n, _ := store.CountUsers(ctx); n != 0 {
+		t.Errorf("reopened store holds %d users, want 0", n)
+	}
+}
+
+func TestNewBoltStoreKeepsUnseededData(t *testing.T) {
+	dir := t.TempDir()
+	seedPath := filepath.Join(dir, "users.json")
+	if err := os.WriteFile(seedPath, []byte(seedUsers), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dbPath := filepath.Join(dir, "users.db")
+	ctx := context.Background()
+
+	// A store filled without a seed file, e.g. by the importer
+	store, err := NewBoltStore(dbPath, "", LoadOptions{})
+	if err != nil {
+		t.Fatalf("NewBoltStore: %v", err)
+	}
+	if _, err := store.CreateUser(ctx, &pb.User{Id: 7, Fname: "Ann", City: "Oslo", Phone: 1, Height: 170}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	store.Close()
+
+	store, err = NewBoltStore(dbPath, seedPath, LoadOptions{})
+	if err != nil {
+		t.Fatalf("reopening: %v", err)
+	}
+	defer store.Close()
+	if n, _ := store.CountUsers(ctx); n != 1 {
+		t.Errorf("store holds %d users, want only the one created", n)
+	}
+}
+
+func TestBoltStoreScanUsersInBatches(t *testing.T) {
+	store, err := NewBoltStore(filepath.Join(t.TempDir(), "users.db"), "", LoadOptions{})
+	if err != nil {
+		t.Fatalf("NewBoltStore: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+	total := 2*scanBatchSize + 10
+	for id := 1; id <= total; id++ {
+		city := "Oslo"
+		if id%2 == 0 {
+			city = "Bergen"
+		}
+		if _, err := store.CreateUser(ctx, &pb.User{Id: int32(id), Fname: "user", City: city, Phone: int64(id), Height: 170}); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+	}
+
+	// fn writes between batches, which bolt blocks while a read transaction of
+	// the same goroutine is open and the memory map has to grow
+	var got []int32
+	err = store.ScanUsers(ctx, Query{}, func(user *pb.User) error {
+		got = append(got, user.Id)
+		if len(got)%scanBatchSize == 0 {
+			_, err := store.CreateUser(ctx, &pb.User{Id: int32(10000 + len(got)), Fname: "late", City: "Oslo", Phone: 1, Height: 170})
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ScanUsers: %v", err)
+	}
+	// Users inserted after the batch being read are streamed as well
+	if want := total + 2; len(got) != want {
+		t.Fatalf("scanned %d users, want %d", len(got), want)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i] <= got[i-1] {
+			t.Fatalf("user %d streamed after %d, want ID order", got[i], got[i-1])
+		}
+	}
+
+	// Batches only count matching users
+	var oslo int
+	q := Query{Criteria: []*pb.SearchCriteria{{FieldName: "city", FieldValue: "Oslo"}}}
+	if err := store.ScanUsers(ctx, q, func(*pb.User) error { oslo++; return nil }); err != nil {
+		t.Fatalf("ScanUsers: %v", err)
+	}
+	if want := total/2 + 2; oslo != want {
+		t.Errorf("scanned %d users in Oslo, want %d", oslo, want)
+	}
+}
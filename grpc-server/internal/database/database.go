@@ -1,152 +1,286 @@
 package database
 
 import (
-	"encoding/json"
-	"errors"
-	"fmt"
-	"os"
-	"strconv"
+	"context"
+	"sync"
 
 	"github.com/ParasJain0307/grpc-project/grpc-server/internal/logger"
 	"github.com/ParasJain0307/grpc-project/grpc-server/internal/utils"
-	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
 
 	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
 )
 
+// allFields lists every updatable User field, used when no update mask is given
+var allFields = []string{utils.FIRSTNAME, utils.CITY, utils.PHONE, utils.HEIGHT, utils.MARRIED}
+
+// Database is the in-memory UserStore backed by a JSON file
 type Database struct {
-	users map[int32]*pb.User
+	mu     sync.RWMutex
+	users  map[int32]*pb.User
+	index  *userIndex // Secondary indexes, nil when indexing is disabled
+	nextID int32
+	load   LoadOptions // Options the JSON file is loaded and reloaded with
+	reload reloader    // Watches the JSON file for changes, see Watch
 }
 
 // NewDatabase initializes a new database instance
-func NewDatabase(jsonPath string) (*Database, error) {
+func NewDatabase(jsonPath string, opts LoadOptions) (*Database, error) {
 	logger.Info("Initializing new database from JSON file")
 
-	// Read JSON file
-	jsonData, err := os.ReadFile(jsonPath)
+	users, err := loadUsers(jsonPath, opts)
 	if err != nil {
-		logger.Error("Failed to read JSON file", zap.Error(err))
-		return nil, fmt.Errorf("error reading JSON file: %v", err)
+		return nil, err
 	}
 
-	// Unmarshal JSON directly into a slice of map[string]interface{}
-	var users []map[string]interface{}
-	err = json.Unmarshal(jsonData, &users)
-	if err != nil {
-		logger.Error("Failed to unmarshal JSON data", zap.Error(err))
-		return nil, fmt.Errorf("error unmarshalling JSON data: %v", err)
-	}
+	db := NewDatabaseFromUsers(users, true)
+	db.load = opts
+	logger.Info("Database initialization complete")
+	return db, nil
+}
 
+// NewDatabaseFromUsers creates a database holding users. When indexed is set,
+// secondary indexes are maintained so searches avoid scanning every user.
+func NewDatabaseFromUsers(users []*pb.User, indexed bool) *Database {
 	// Initialize map with user IDs as keys
-	userMap := make(map[int32]*pb.User)
-	for _, u := range users {
-		id, ok := u["id"].(float64) // assuming "id" field is float64 in JSON
-		if !ok {
-			logger.Warn("Failed to parse user ID")
-			continue
-		}
-		user := &pb.User{
-			Id:      int32(id),
-			Fname:   u["fname"].(string),
-			City:    u["city"].(string),
-			Phone:   int64(u["phone"].(float64)),
-			Height:  float32(u["height"].(float64)),
-			Married: u["married"].(bool),
-		}
+	userMap := make(map[int32]*pb.User, len(users))
+	var maxID int32
+	for _, user := range users {
 		userMap[user.Id] = user
+		if user.Id > maxID {
+			maxID = user.Id
+		}
 	}
-	logger.Info("Database initialization complete")
-	return &Database{
-		users: userMap,
-	}, nil
+	db := &Database{
+		users:  userMap,
+		nextID: maxID + 1,
+	}
+	if indexed {
+		db.index = newUserIndex(userMap)
+	}
+	return db
 }
 
 // GetUserByID retrieves a user by ID from the datastore
-func (d *Database) GetUserByID(id int32) (*pb.User, error) {
-	logger.Debugf("Fetching user with ID %v", id)
+func (d *Database) GetUserByID(ctx context.Context, id int32) (*pb.User, error) {
+	logger.FromContext(ctx).Debugf("Fetching user with ID %v", id)
+	d.mu.RLock()
+	defer d.mu.RUnlock()
 	user, ok := d.users[id]
 	if !ok {
-		logger.Warnf("User with ID %v not found", id)
-		return nil, errors.New("user not found")
+		logger.FromContext(ctx).Warnf("User with ID %v not found", id)
+		return nil, ErrUserNotFound
 	}
-	logger.Infof("User with ID %v found", id)
+	logger.FromContext(ctx).Infof("User with ID %v found", id)
 	return user, nil
 }
 
 // GetUsersByID retrieves a list of users by IDs from the datastore
-func (d *Database) GetUsersByID(ids []int32) ([]*pb.User, error) {
+func (d *Database) GetUsersByID(ctx context.Context, ids []int32) ([]*pb.User, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
 	var users []*pb.User
 	for _, id := range ids {
 		user, ok := d.users[id]
 		if ok {
 			users = append(users, user)
-			logger.Debugf("User with ID %v added to result", id)
+			logger.FromContext(ctx).Debugf("User with ID %v added to result", id)
 		} else {
-			logger.Warnf("User with ID %v not found", id)
+			logger.FromContext(ctx).Warnf("User with ID %v not found", id)
 		}
 	}
-	logger.Infof("Retrieved %v users by IDs", len(users))
+	logger.FromContext(ctx).Infof("Retrieved %v users by IDs", len(users))
 	return users, nil
 }
 
-// SearchUsers searches users based on criteria in the datastore
-func (d *Database) SearchUsers(criteria []*pb.SearchCriteria) ([]*pb.User, error) {
+// SearchUsers searches users matching the query in the datastore
+func (d *Database) SearchUsers(ctx context.Context, q Query) ([]*pb.User, error) {
+	return traceSearch(ctx, "Database.SearchUsers", BackendMemory, q, func() ([]*pb.User, error) {
+		return d.searchUsers(ctx, q)
+	})
+}
+
+func (d *Database) searchUsers(ctx context.Context, q Query) ([]*pb.User, error) {
+	match, err := compileQuery(q)
+	if err != nil {
+		logger.FromContext(ctx).Warnf("Rejected search query: %v", err)
+		return nil, err
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
 	var users []*pb.User
-	for _, user := range d.users {
-		// Example search criteria (can be customized)
-		if matchCriteria(user, criteria) {
+	d.eachCandidate(q, func(user *pb.User) {
+		if match(user) {
 			users = append(users, user)
 		}
-
-	}
+	})
 	if len(users) == 0 {
-		logger.Warn("No users found matching the criteria")
-		return nil, errors.New("no users found")
+		logger.FromContext(ctx).Warn("No users found matching the criteria")
+		return nil, ErrNoUsersFound
 	}
-	logger.Infof("Found %v users matching the criteria", len(users))
+	logger.FromContext(ctx).Infof("Found %v users matching the criteria", len(users))
 	return users, nil
 }
 
-// Function to check if a user matches the search criteria
-func matchCriteria(user *pb.User, criterias []*pb.SearchCriteria) bool {
-	// Iterate over criterias and check each one against user's attributes
-	for _, criteria := range criterias {
-		if !checkSingleCriteria(user, criteria) {
-			return false
+// ScanUsers streams the users matching the query to fn. Records are never
+// mutated in place, so matching runs on a snapshot without holding the lock
+// while fn sends results to a possibly slow client.
+func (d *Database) ScanUsers(ctx context.Context, q Query, fn func(*pb.User) error) error {
+	return traceScan(ctx, "Database.ScanUsers", BackendMemory, q, fn, func(ctx context.Context, fn func(*pb.User) error) error {
+		return d.scanUsers(ctx, q, fn)
+	})
+}
+
+func (d *Database) scanUsers(ctx context.Context, q Query, fn func(*pb.User) error) error {
+	match, err := compileQuery(q)
+	if err != nil {
+		logger.FromContext(ctx).Warnf("Rejected search query: %v", err)
+		return err
+	}
+	d.mu.RLock()
+	var snapshot []*pb.User
+	d.eachCandidate(q, func(user *pb.User) {
+		snapshot = append(snapshot, user)
+	})
+	d.mu.RUnlock()
+
+	for _, user := range snapshot {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if match(user) {
+			if err := fn(user); err != nil {
+				return err
+			}
 		}
 	}
-	return true
+	return nil
 }
 
-// checkSingleCriteria checks if a user matches a single criteria
-func checkSingleCriteria(user *pb.User, criteria *pb.SearchCriteria) bool {
-	switch criteria.FieldName {
-	case utils.FIRSTNAME:
-		if user.Fname != criteria.FieldValue {
-			return false
+// CreateUser stores a new user, assigning the next free ID when user.Id is zero
+func (d *Database) CreateUser(ctx context.Context, user *pb.User) (*pb.User, error) {
+	if user == nil {
+		return nil, invalidArgument("user", "user is required")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	created := proto.Clone(user).(*pb.User)
+	if created.Id == 0 {
+		created.Id = d.nextID
+	}
+	if created.Id < 0 {
+		logger.FromContext(ctx).Warnf("Rejected user with invalid ID %v", created.Id)
+		return nil, invalidArgument("user.id", "must be greater than 0")
+	}
+	if _, ok := d.users[created.Id]; ok {
+		logger.FromContext(ctx).Warnf("User with ID %v already exists", created.Id)
+		return nil, ErrUserExists
+	}
+	d.users[created.Id] = created
+	if d.index != nil {
+		d.index.add(created)
+	}
+	if created.Id >= d.nextID {
+		d.nextID = created.Id + 1
+	}
+	logger.FromContext(ctx).Infof("User with ID %v created", created.Id)
+	return created, nil
+}
+
+// UpdateUser overwrites the fields named in paths with the values from user.
+// An empty paths slice replaces every field except the ID.
+func (d *Database) UpdateUser(ctx context.Context, user *pb.User, paths []string) (*pb.User, error) {
+	if user == nil {
+		return nil, invalidArgument("user", "user is required")
+	}
+	if len(paths) == 0 {
+		paths = allFields
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	existing, ok := d.users[user.Id]
+	if !ok {
+		logger.FromContext(ctx).Warnf("User with ID %v not found", user.Id)
+		return nil, ErrUserNotFound
+	}
+	// Apply the update to a copy so readers holding the old record are unaffected
+	updated := proto.Clone(existing).(*pb.User)
+	for _, path := range paths {
+		if err := applyField(updated, user, path); err != nil {
+			logger.FromContext(ctx).Warnf("Rejected update of user with ID %v: %v", user.Id, err)
+			return nil, err
 		}
-	case utils.CITY:
-		if user.City != criteria.FieldValue {
-			return false
+	}
+	d.users[updated.Id] = updated
+	if d.index != nil {
+		d.index.remove(existing)
+		d.index.add(updated)
+	}
+	logger.FromContext(ctx).Infof("User with ID %v updated", updated.Id)
+	return updated, nil
+}
+
+// DeleteUser removes a user by ID from the datastore
+func (d *Database) DeleteUser(ctx context.Context, id int32) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	existing, ok := d.users[id]
+	if !ok {
+		logger.FromContext(ctx).Warnf("User with ID %v not found", id)
+		return ErrUserNotFound
+	}
+	delete(d.users, id)
+	if d.index != nil {
+		d.index.remove(existing)
+	}
+	logger.FromContext(ctx).Infof("User with ID %v deleted", id)
+	return nil
+}
+
+// CountUsers returns the number of users in the datastore
+func (d *Database) CountUsers(ctx context.Context) (int, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.users), nil
+}
+
+// eachCandidate calls fn for every user that may match q, narrowed down by the
+// secondary indexes when one applies. q must be valid and d.mu must be held.
+func (d *Database) eachCandidate(q Query, fn func(*pb.User)) {
+	if d.index != nil {
+		if ids, ok := d.index.plan(q, d.users); ok {
+			for _, id := range ids {
+				fn(d.users[id])
+			}
+			return
 		}
+	}
+	for _, user := range d.users {
+		fn(user)
+	}
+}
+
+// Close stops watching the JSON file; the in-memory store holds no other resources
+func (d *Database) Close() error {
+	d.stopWatching()
+	return nil
+}
+
+// applyField copies a single field named by path from src to dst
+func applyField(dst, src *pb.User, path string) error {
+	switch path {
+	case utils.FIRSTNAME:
+		dst.Fname = src.Fname
+	case utils.CITY:
+		dst.City = src.City
 	case utils.PHONE:
-		phone, err := strconv.ParseInt(criteria.FieldValue, 10, 64)
-		if err != nil || user.Phone != phone {
-			return false
-		}
+		dst.Phone = src.Phone
 	case utils.HEIGHT:
-		height, err := strconv.ParseFloat(criteria.FieldValue, 32)
-		if err != nil || float32(height) != user.Height {
-			return false
-		}
+		dst.Height = src.Height
 	case utils.MARRIED:
-		married, err := strconv.ParseBool(criteria.FieldValue)
-		if err != nil || user.Married != married {
-			return false
-		}
+		dst.Married = src.Married
 	default:
-		return false
+		return invalidArgument("update_mask.paths", "field %q cannot be updated", path)
 	}
-
-	return true
+	return nil
 }
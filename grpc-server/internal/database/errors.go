@@ -0,0 +1,41 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors returned by every UserStore implementation
+var (
+	ErrUserNotFound     = errors.New("user not found")
+	ErrNoUsersFound     = errors.New("no users found")
+	ErrUserExists       = errors.New("user already exists")
+	ErrStoreUnavailable = errors.New("user store unavailable")
+)
+
+// FieldViolation describes a single invalid request field
+type FieldViolation struct {
+	Field       string // Path of the offending field, e.g. "criterias[0].field_value"
+	Description string // Why the value was rejected
+}
+
+// InvalidArgumentError reports request fields that failed validation
+type InvalidArgumentError struct {
+	Violations []FieldViolation
+}
+
+func (e *InvalidArgumentError) Error() string {
+	msgs := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		msgs = append(msgs, v.Field+": "+v.Description)
+	}
+	return "invalid argument: " + strings.Join(msgs, "; ")
+}
+
+// invalidArgument returns an InvalidArgumentError holding a single violation
+func invalidArgument(field, format string, args ...interface{}) error {
+	return &InvalidArgumentError{
+		Violations: []FieldViolation{{Field: field, Description: fmt.Sprintf(format, args...)}},
+	}
+}
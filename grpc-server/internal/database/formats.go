@@ -0,0 +1,229 @@
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxNDJSONLine is the longest line accepted in an NDJSON users file
+const maxNDJSONLine = 1 << 20
+
+// recordReader streams the records of a users file to fn in file order. It
+// returns an error only when the file itself is malformed; problems with a
+// single record are reported through rawRecord.
+type recordReader func(r io.Reader, fn func(rawRecord)) error
+
+// recordReaders holds the reader of every supported format
+var recordReaders = map[string]recordReader{
+	FormatJSON:   readJSONArray,
+	FormatNDJSON: readNDJSON,
+	FormatCSV:    readCSV,
+	FormatYAML:   readYAML,
+}
+
+// readJSONArray decodes the elements of a JSON array one at a time
+func readJSONArray(r io.Reader, fn func(rawRecord)) error {
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return errors.New("expected a JSON array of users")
+	}
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+		fn(jsonRecord(raw, 0))
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("unexpected data after the JSON array")
+	}
+	return nil
+}
+
+// readNDJSON decodes one JSON object per line, skipping blank lines
+func readNDJSON(r io.Reader, fn func(rawRecord)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLine)
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		// The scanner reuses its buffer for the next line
+		fn(jsonRecord(append(json.RawMessage(nil), raw...), line))
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("line %d: %v", line+1, err)
+	}
+	return nil
+}
+
+// jsonRecord splits a JSON object into its undecoded fields
+func jsonRecord(raw json.RawMessage, line int) rawRecord {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &values); err != nil || values == nil {
+		if !json.Valid(raw) {
+			return rawRecord{line: line, invalid: "invalid JSON"}
+		}
+		return rawRecord{line: line, invalid: "record must be a JSON object"}
+	}
+	fields := make(map[string]fieldValue, len(values))
+	for name, value := range values {
+		fields[name] = fieldValue{
+			text:   string(value),
+			decode: func(target interface{}) error { return json.Unmarshal(value, target) },
+		}
+	}
+	return rawRecord{line: line, fields: fields}
+}
+
+// readCSV reads a header row naming the fields, then one user per row. Empty
+// cells are treated as missing fields.
+func readCSV(r io.Reader, fn func(rawRecord)) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool)
+	for _, f := range (&userRecord{}).fields() {
+		known[f.name] = true
+	}
+	columns := make(map[string]bool, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		switch {
+		case !known[name]:
+			return fmt.Errorf("unknown column %q in header", name)
+		case columns[name]:
+			return fmt.Errorf("duplicate column %q in header", name)
+		}
+		columns[name] = true
+		header[i] = name
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := reader.FieldPos(0)
+		if len(row) != len(header) {
+			fn(rawRecord{line: line, invalid: fmt.Sprintf("row has %d fields, header has %d", len(row), len(header))})
+			continue
+		}
+		fields := make(map[string]fieldValue, len(row))
+		for i, cell := range row {
+			if cell == "" {
+				continue
+			}
+			fields[header[i]] = fieldValue{
+				text:   strconv.Quote(cell),
+				decode: func(target interface{}) error { return parseCSVValue(cell, target) },
+			}
+		}
+		fn(rawRecord{line: line, fields: fields})
+	}
+}
+
+// parseCSVValue parses a CSV cell into a pointer to a userRecord field
+func parseCSVValue(cell string, target interface{}) error {
+	cell = strings.TrimSpace(cell)
+	switch t := target.(type) {
+	case **string:
+		*t = &cell
+	case **int64:
+		v, err := strconv.ParseInt(cell, 10, 64)
+		if err != nil {
+			return err
+		}
+		*t = &v
+	case **float32:
+		v, err := parseFloat32(cell)
+		if err != nil {
+			return err
+		}
+		*t = &v
+	case **bool:
+		v, err := strconv.ParseBool(cell)
+		if err != nil {
+			return err
+		}
+		*t = &v
+	default:
+		return fmt.Errorf("unsupported field type %T", target)
+	}
+	return nil
+}
+
+// readYAML reads a sequence of user mappings from each YAML document in turn,
+// so large files can be split into documents to bound memory use
+func readYAML(r io.Reader, fn func(rawRecord)) error {
+	dec := yaml.NewDecoder(r)
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+		root := doc.Content[0]
+		if root.Kind != yaml.SequenceNode {
+			return fmt.Errorf("line %d: expected a sequence of users", root.Line)
+		}
+		for _, item := range root.Content {
+			fn(yamlRecord(item))
+		}
+	}
+}
+
+// yamlRecord splits a YAML mapping into its undecoded fields
+func yamlRecord(node *yaml.Node) rawRecord {
+	if node.Kind != yaml.MappingNode {
+		return rawRecord{line: node.Line, invalid: "record must be a mapping"}
+	}
+	fields := make(map[string]fieldValue, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		text := value.Value
+		if value.Kind != yaml.ScalarNode {
+			text = "of kind " + value.ShortTag()
+		} else if value.ShortTag() == "!!str" {
+			text = strconv.Quote(text)
+		}
+		fields[key.Value] = fieldValue{text: text, decode: value.Decode}
+	}
+	return rawRecord{line: node.Line, fields: fields}
+}
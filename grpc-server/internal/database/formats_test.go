@@ -0,0 +1,210 @@
+package database
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
+	"google.golang.org/protobuf/proto"
+)
+
+// formatUsers is the content of each format's fixture
+var formatUsers = []*pb.User{
+	{Id: 1, Fname: "John", City: "New York", Phone: 1234567890, Height: 180.5, Married: true},
+	{Id: 2, Fname: "Jane", City: "Los Angeles, CA", Phone: 2345678901, Height: 165, Married: false},
+}
+
+// formatFixtures hold formatUsers in every format, by file name
+var formatFixtures = map[string]string{
+	"users.json": `[
+  {"id": 1, "fname": "John", "city": "New York", "phone": 1234567890, "height": 180.5, "married": true},
+  {"married": false, "height": 165, "phone": 2345678901, "city": "Los Angeles, CA", "fname": "Jane", "id": 2}
+]`,
+	"users.ndjson": `{"id": 1, "fname": "John", "city": "New York", "phone": 1234567890, "height": 180.5, "married": true}
+{"married": false, "height": 165, "phone": 2345678901, "city": "Los Angeles, CA", "fname": "Jane", "id": 2}
+`,
+	"users.csv": `id, fname, city, phone, height, married
+1, John, New York, 1234567890, 180.5, true
+2,Jane,"Los Angeles, CA",2345678901,165,false
+`,
+	// Split across documents, as large files may be
+	"users.yaml": `- id: 1
+  fname: John
+  city: New York
+  phone: 1234567890
+  height: 180.5
+  married: true
+---
+- {id: 2, fname: Jane, city: "Los Angeles, CA", phone: 2345678901, height: 165, married: false}
+`,
+}
+
+func TestLoadUsersOfEveryFormat(t *testing.T) {
+	for name, content := range formatFixtures {
+		t.Run(name, func(t *testing.T) {
+			users, err := loadUsers(writeUsersFile(t, name, content), LoadOptions{})
+			if err != nil {
+				t.Fatalf("loadUsers: %v", err)
+			}
+			if !slices.EqualFunc(users, formatUsers, func(a, b *pb.User) bool { return proto.Equal(a, b) }) {
+				t.Errorf("loaded %v, want %v", users, formatUsers)
+			}
+		})
+	}
+}
+
+func TestLoadUsersReportsRecordLines(t *testing.T) {
+	tests := []struct {
+		name, content string
+		want          []RecordError
+	}{
+		// A JSON array does not track lines
+		{"users.json", `[
+  {"id": 1, "fname": "John", "city": "Oslo", "phone": 1, "height": 1, "married": true},
+  {"id": 2, "fname": "Jane", "phone": 2, "height": 1, "married": true}
+]`, []RecordError{{Index: 1, Reason: "city is required"}}},
+		{"users.ndjson", `
+{"id": 1, "fname": "John", "city": "Oslo", "phone": 1, "height": 1, "married": true}
+
+{"id": 2, "fname": "Jane", "phone": 2, "height": 1, "married": true}
+`, []RecordError{{Index: 1, Line: 4, Reason: "city is required"}}},
+		// A quoted cell may span lines, records start where their first cell does
+		{"users.csv", `id,fname,city,phone,height,married
+1,John,"New
+York",1,1,true
+2,Jane,,2,1,true
+3,Ann,Oslo
+`, []RecordError{
+			{Index: 1, Line: 4, Reason: "city is required"},
+			{Index: 2, Line: 5, Reason: "row has 3 fields, header has 6"},
+		}},
+		{"users.yaml", `# users
+- id: 1
+  fname: John
+  city: Oslo
+  phone: 1
+  height: 1
+  married: true
+---
+- {id: 2, fname: Jane, phone: 2, height: 1, married: true}
+- not a mapping
+`, []RecordError{
+			{Index: 1, Line: 9, Reason: "city is required"},
+			{Index: 2, Line: 10, Reason: "record must be a mapping"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadUsers(writeUsersFile(t, tt.name, tt.content), LoadOptions{})
+			var loadErr *LoadError
+			if !errors.As(err, &loadErr) {
+				t.Fatalf("error = %v, want a LoadError", err)
+			}
+			if !slices.Equal(loadErr.Records, tt.want) {
+				t.Errorf("records = %+v, want %+v", loadErr.Records, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadUsersReportsMalformedValues(t *testing.T) {
+	tests := []struct {
+		name, content, reason string
+	}{
+		{"users.csv", "id,fname,city,phone,height,married\n1,John,Oslo,1e3,tall,yes\n",
+			`phone has invalid value "1e3", height has invalid value "tall", married has invalid value "yes"`},
+		{"users.yaml", "- {id: 1, fname: [John], city: Oslo, phone: abc, height: 1, married: true}\n",
+			`fname has invalid value of kind !!seq, phone has invalid value "abc"`},
+		{"users.json", `[{"id": 1.5, "fname": 7, "city": "Oslo", "phone": 1, "height": "1", "married": "true"}]`,
+			"id has invalid value 1.5, fname has invalid value 7, height has invalid value \"1\", married has invalid value \"true\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadUsers(writeUsersFile(t, tt.name, tt.content), LoadOptions{})
+			var loadErr *LoadError
+			if !errors.As(err, &loadErr) {
+				t.Fatalf("error = %v, want a LoadError", err)
+			}
+			if len(loadErr.Records) != 1 || loadErr.Records[0].Reason != tt.reason {
+				t.Errorf("records = %+v, want the reason %q", loadErr.Records, tt.reason)
+			}
+		})
+	}
+}
+
+func TestLoadUsersRejectsMalformedFiles(t *testing.T) {
+	tests := []struct {
+		name, file, content, err string
+	}{
+		{"JSON object instead of array", "users.json", `{"id": 1}`, "expected a JSON array"},
+		{"truncated JSON array", "users.json", `[{"id": 1}`, "unexpected end of JSON input"},
+		{"data after the JSON array", "users.json", `[] []`, "unexpected data after the JSON array"},
+		{"NDJSON line too long", "users.ndjson", "{}\n" + strings.Repeat(" ", maxNDJSONLine+1), "line 2"},
+		{"unknown CSV column", "users.csv", "id,fname,age\n", `unknown column "age"`},
+		{"duplicate CSV column", "users.csv", "id, city, city\n", `duplicate column "city"`},
+		{"bare quote in CSV", "users.csv", "id,fname\n1,Jo\"hn\n", "bare \""},
+		{"YAML mapping instead of sequence", "users.yaml", "id: 1\n", "line 1: expected a sequence of users"},
+		{"invalid YAML", "users.yaml", "- [1, 2\n", "yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, err := loadUsers(writeUsersFile(t, tt.file, tt.content), LoadOptions{Mode: LoadLenient})
+			if err == nil {
+				t.Fatalf("loaded %d users from a malformed file", len(users))
+			}
+			var loadErr *LoadError
+			if errors.As(err, &loadErr) {
+				t.Fatalf("error = %v, want the file rather than its records rejected", err)
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error = %v, want it to mention %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestLoadUsersOfEmptyFiles(t *testing.T) {
+	for name, content := range map[string]string{
+		"users.json":   "[]",
+		"users.ndjson": "\n\n",
+		"users.csv":    "",
+		"users.yaml":   "",
+	} {
+		users, err := loadUsers(writeUsersFile(t, name, content), LoadOptions{})
+		if err != nil || len(users) != 0 {
+			t.Errorf("%s: loaded %d users, %v; want none", name, len(users), err)
+		}
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		path, format, want string
+	}{
+		{"users.json", "", FormatJSON},
+		{"users.ndjson", "", FormatNDJSON},
+		{"USERS.JSONL", "", FormatNDJSON},
+		{"users.csv", "", FormatCSV},
+		{"users.yaml", "", FormatYAML},
+		{"users.yml", "", FormatYAML},
+		{"users.txt", FormatCSV, FormatCSV},
+		{"users.json", FormatNDJSON, FormatNDJSON},
+		{"users.txt", "", ""},
+		{"users", "", ""},
+		{"users.json", "xml", ""},
+	}
+	for _, tt := range tests {
+		got, err := detectFormat(tt.path, tt.format)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("detectFormat(%q, %q) = %q, want an error", tt.path, tt.format, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("detectFormat(%q, %q) = %q, %v; want %q", tt.path, tt.format, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package database
+
+import (
+	"sort"
+	"strconv"
+
+	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/utils"
+)
+
+// postings maps a field value to the IDs of the users holding it
+type postings[K comparable] map[K]map[int32]struct{}
+
+func (p postings[K]) add(key K, id int32) {
+	ids, ok := p[key]
+	if !ok {
+		ids = make(map[int32]struct{})
+		p[key] = ids
+	}
+	ids[id] = struct{}{}
+}
+
+func (p postings[K]) remove(key K, id int32) {
+	ids := p[key]
+	delete(ids, id)
+	if len(ids) == 0 {
+		delete(p, key)
+	}
+}
+
+// heightEntry is one row of the sorted height index
+type heightEntry struct {
+	height float32
+	id     int32
+}
+
+func (e heightEntry) less(o heightEntry) bool {
+	return e.height < o.height || (e.height == o.height && e.id < o.id)
+}
+
+// userIndex holds the secondary indexes of the in-memory store: equality
+// postings for fname, city, married and phone and a sorted index on height
+type userIndex struct {
+	fname   postings[string]
+	city    postings[string]
+	married postings[bool]
+	phone   postings[int64]
+	height  []heightEntry // Sorted by height, then id
+}
+
+// newUserIndex builds the indexes for users in a single pass
+func newUserIndex(users map[int32]*pb.User) *userIndex {
+	idx := &userIndex{
+		fname:   make(postings[string]),
+		city:    make(postings[string]),
+		married: make(postings[bool]),
+		phone:   make(postings[int64]),
+		height:  make([]heightEntry, 0, len(users)),
+	}
+	for _, user := range users {
+		idx.addPostings(user)
+		idx.height = append(idx.height, heightEntry{height: user.Height, id: user.Id})
+	}
+	sort.Slice(idx.height, func(i, j int) bool { return idx.height[i].less(idx.height[j]) })
+	return idx
+}
+
+// add indexes a newly stored user
+func (idx *userIndex) add(user *pb.User) {
+	idx.addPostings(user)
+	entry := heightEntry{height: user.Height, id: user.Id}
+	i := sort.Search(len(idx.height), func(i int) bool { return !idx.height[i].less(entry) })
+	idx.height = append(idx.height, heightEntry{})
+	copy(idx.height[i+1:], idx.height[i:])
+	idx.height[i] = entry
+}
+
+// remove drops a stored user from every index
+func (idx *userIndex) remove(user *pb.User) {
+	idx.fname.remove(user.Fname, user.Id)
+	idx.city.remove(user.City, user.Id)
+	idx.married.remove(user.Married, user.Id)
+	idx.phone.remove(user.Phone, user.Id)
+	entry := heightEntry{height: user.Height, id: user.Id}
+	i := sort.Search(len(idx.height), func(i int) bool { return !idx.height[i].less(entry) })
+	if i < len(idx.height) && idx.height[i] == entry {
+		idx.height = append(idx.height[:i], idx.height[i+1:]...)
+	}
+}
+
+func (idx *userIndex) addPostings(user *pb.User) {
+	idx.fname.add(user.Fname, user.Id)
+	idx.city.add(user.City, user.Id)
+	idx.married.add(user.Married, user.Id)
+	idx.phone.add(user.Phone, user.Id)
+}
+
+// indexScan is a conjunct of a query the indexes can answer
+type indexScan struct {
+	estimate int                     // Number of IDs the scan yields
+	each     func(fn func(id int32)) // Calls fn for every ID, without duplicates
+	contains func(id int32) bool     // Reports whether the scan would yield id
+}
+
+// plan returns the IDs of the users that may match q, using the most selective
+// index to drive the scan and intersecting it with the other indexed conjuncts.
+// ok is false when no conjunct of q is indexed and a full scan is required.
+// q must already have been validated by compileQuery.
+func (idx *userIndex) plan(q Query, users map[int32]*pb.User) (ids []int32, ok bool) {
+	b := &scanBuilder{idx: idx, users: users}
+	for _, criteria := range q.Criteria {
+		b.addCriteria(criteria)
+	}
+	if q.Filter != nil {
+		b.addFilter(q.Filter)
+	}
+	scans := b.build()
+	if len(scans) == 0 {
+		return nil, false
+	}
+
+	// Most selective index first
+	sort.Slice(scans, func(i, j int) bool { return scans[i].estimate < scans[j].estimate })
+	driver, rest := scans[0], scans[1:]
+	ids = make([]int32, 0, driver.estimate)
+	driver.each(func(id int32) {
+		for _, scan := range rest {
+			if !scan.contains(id) {
+				return
+			}
+		}
+		ids = append(ids, id)
+	})
+	return ids, true
+}
+
+// scanBuilder collects the index scans for terms that are ANDed together,
+// merging every height comparison into a single range of the sorted index
+type scanBuilder struct {
+	idx    *userIndex
+	users  map[int32]*pb.User
+	scans  []*indexScan
+	lo, hi heightBound
+}
+
+// addCriteria adds the scan for a single criteria, if it is indexed
+func (b *scanBuilder) addCriteria(criteria *pb.SearchCriteria) {
+	if criteria.GetFieldName() == utils.HEIGHT {
+		if height, err := parseFloat32(criteria.GetFieldValue()); err == nil {
+			switch criteria.GetOperator() {
+			case pb.Operator_LT, pb.Operator_LTE:
+				b.hi = b.hi.tighten(heightBound{value: height, inclusive: criteria.GetOperator() == pb.Operator_LTE, set: true}, true)
+				return
+			case pb.Operator_GT, pb.Operator_GTE:
+				b.lo = b.lo.tighten(heightBound{value: height, inclusive: criteria.GetOperator() == pb.Operator_GTE, set: true}, false)
+				return
+			}
+		}
+	}
+	if scan := b.idx.criteriaScan(criteria, b.users); scan != nil {
+		b.scans = append(b.scans, scan)
+	}
+}
+
+// addFilter adds the scans for a filter ANDed with the other terms
+func (b *scanBuilder) addFilter(f *pb.SearchFilter) {
+	switch node := f.GetFilter().(type) {
+	case *pb.SearchFilter_Criteria:
+		b.addCriteria(node.Criteria)
+	case *pb.SearchFilter_Group:
+		switch node.Group.GetLogic() {
+		case pb.FilterGroup_AND:
+			for _, child := range node.Group.GetFilters() {
+				b.addFilter(child)
+			}
+		case pb.FilterGroup_OR:
+			// Usable only when every branch is indexed; each branch then
+			// contributes its most selective scan to the union
+			var branches []*indexScan
+			for _, child := range node.Group.GetFilters() {
+				branch := &scanBuilder{idx: b.idx, users: b.users}
+				branch.addFilter(child)
+				scans := branch.build()
+				if len(scans) == 0 {
+					return
+				}
+				best := scans[0]
+				for _, scan := range scans[1:] {
+					if scan.estimate < best.estimate {
+						best = scan
+					}
+				}
+				branches = append(branches, best)
+			}
+			b.scans = append(b.scans, unionScan(branches))
+		}
+	}
+}
+
+// build returns the collected scans, including the merged height range
+func (b *scanBuilder) build() []*indexScan {
+	if b.lo.set || b.hi.set {
+		return append(b.scans, b.idx.heightRange(b.lo, b.hi, b.users))
+	}
+	return b.scans
+}
+
+// criteriaScan returns the index scan answering criteria, or nil when it is not indexed
+func (idx *userIndex) criteriaScan(criteria *pb.SearchCriteria, users map[int32]*pb.User) *indexScan {
+	values := []string{criteria.GetFieldValue()}
+	switch criteria.GetOperator() {
+	case pb.Operator_EQ:
+	case pb.Operator_IN:
+		values = criteria.GetFieldValues()
+	default:
+		return nil
+	}
+
+	switch criteria.GetFieldName() {
+	case utils.FIRSTNAME:
+		return postingsScan(idx.fname, values, parseString)
+	case utils.CITY:
+		return postingsScan(idx.city, values, parseString)
+	case utils.MARRIED:
+		return postingsScan(idx.married, values, strconv.ParseBool)
+	case utils.PHONE:
+		return postingsScan(idx.phone, values, parseInt)
+	case utils.HEIGHT:
+		var scans []*indexScan
+		for _, v := range values {
+			height, err := parseFloat32(v)
+			if err != nil {
+				return nil
+			}
+			bound := heightBound{value: height, inclusive: true, set: true}
+			scans = append(scans, idx.heightRange(bound, bound, users))
+		}
+		return unionScan(scans)
+	default:
+		return nil
+	}
+}
+
+// postingsScan yields the IDs holding any of values
+func postingsScan[K comparable](p postings[K], values []string, parse func(string) (K, error)) *indexScan {
+	var sets []map[int32]struct{}
+	seen := make(map[K]bool, len(values))
+	estimate := 0
+	for _, v := range values {
+		key, err := parse(v)
+		if err != nil {
+			return nil
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if ids, ok := p[key]; ok {
+			sets = append(sets, ids)
+			estimate += len(ids)
+		}
+	}
+	return &indexScan{
+		estimate: estimate,
+		each: func(fn func(id int32)) {
+			// Postings of distinct values never overlap
+			for _, ids := range sets {
+				for id := range ids {
+					fn(id)
+				}
+			}
+		},
+		contains: func(id int32) bool {
+			for _, ids := range sets {
+				if _, ok := ids[id]; ok {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// heightBound is one end of a height range; an unset bound leaves that end open
+type heightBound struct {
+	value     float32
+	inclusive bool
+	set       bool
+}
+
+// tighten returns the narrower of two bounds on the same end of a range;
+// upper selects whether they are upper or lower bounds
+func (b heightBound) tighten(o heightBound, upper bool) heightBound {
+	switch {
+	case !b.set:
+		return o
+	case o.value == b.value:
+		b.inclusive = b.inclusive && o.inclusive
+		return b
+	case (o.value < b.value) == upper:
+		return o
+	default:
+		return b
+	}
+}
+
+// heightRange yields the IDs whose height lies between lo and hi
+func (idx *userIndex) heightRange(lo, hi heightBound, users map[int32]*pb.User) *indexScan {
+	aboveLo := func(h float32) bool {
+		return !lo.set || h > lo.value || (h == lo.value && lo.inclusive)
+	}
+	belowHi := func(h float32) bool {
+		return !hi.set || h < hi.value || (h == hi.value && hi.inclusive)
+	}
+
+	start := sort.Search(len(idx.height), func(i int) bool { return aboveLo(idx.height[i].height) })
+	end := sort.Search(len(idx.height), func(i int) bool { return !belowHi(idx.height[i].height) })
+	if end < start {
+		end = start
+	}
+	entries := idx.height[start:end]
+	return &indexScan{
+		estimate: len(entries),
+		each: func(fn func(id int32)) {
+			for _, e := range entries {
+				fn(e.id)
+			}
+		},
+		contains: func(id int32) bool {
+			user, ok := users[id]
+			return ok && aboveLo(user.Height) && belowHi(user.Height)
+		},
+	}
+}
+
+// unionScan yields the IDs of any of scans, each ID once
+func unionScan(scans []*indexScan) *indexScan {
+	if len(scans) == 1 {
+		return scans[0]
+	}
+	estimate := 0
+	for _, scan := range scans {
+		estimate += scan.estimate
+	}
+	return &indexScan{
+		estimate: estimate,
+		each: func(fn func(id int32)) {
+			seen := make(map[int32]struct{}, estimate)
+			for _, scan := range scans {
+				scan.each(func(id int32) {
+					if _, ok := seen[id]; !ok {
+						seen[id] = struct{}{}
+						fn(id)
+					}
+				})
+			}
+		},
+		contains: func(id int32) bool {
+			for _, scan := range scans {
+				if scan.contains(id) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
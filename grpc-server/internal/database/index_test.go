@@ -0,0 +1,293 @@
+package database
+
+import (
+	"context"
+	"flag"
+	"math/rand"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+
+	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/utils"
+)
+
+var benchUsers = flag.Int("bench-users", 100_000, "number of users generated for the search benchmarks")
+
+var (
+	firstNames = []string{"John", "Jane", "Michael", "Emily", "David", "Sarah", "James", "Olivia", "Daniel", "Sophia",
+		"Matthew", "Ava", "Andrew", "Mia", "Joshua", "Chloe", "Ryan", "Grace", "Nathan", "Lily"}
+	cities = []string{"New York", "Los Angeles", "Chicago", "San Francisco", "Seattle", "Boston", "Austin", "Denver",
+		"Miami", "Atlanta", "Dallas", "Houston", "Phoenix", "Portland", "Detroit", "Nashville"}
+)
+
+// generateUsers creates n users with reproducible random fields and unique phones.
+// Heights are multiples of 0.1 between 150 and 199.9, so many users share one.
+func generateUsers(n int, seed int64) []*pb.User {
+	r := rand.New(rand.NewSource(seed))
+	users := make([]*pb.User, n)
+	for i := range users {
+		users[i] = &pb.User{
+			Id:      int32(i + 1),
+			Fname:   firstNames[r.Intn(len(firstNames))],
+			City:    cities[r.Intn(len(cities))],
+			Phone:   1_000_000_000 + int64(i)*7,
+			Height:  float32(1500+r.Intn(500)) / 10,
+			Married: r.Intn(2) == 0,
+		}
+	}
+	return users
+}
+
+func criteria(field string, op pb.Operator, value string) *pb.SearchCriteria {
+	return &pb.SearchCriteria{FieldName: field, Operator: op, FieldValue: value}
+}
+
+func in(field string, values ...string) *pb.SearchCriteria {
+	return &pb.SearchCriteria{FieldName: field, Operator: pb.Operator_IN, FieldValues: values}
+}
+
+func leaf(c *pb.SearchCriteria) *pb.SearchFilter {
+	return &pb.SearchFilter{Filter: &pb.SearchFilter_Criteria{Criteria: c}}
+}
+
+func group(logic pb.FilterGroup_Logic, filters ...*pb.SearchFilter) *pb.SearchFilter {
+	return &pb.SearchFilter{Filter: &pb.SearchFilter_Group{Group: &pb.FilterGroup{Logic: logic, Filters: filters}}}
+}
+
+func TestIndexPlanMatchesFullScan(t *testing.T) {
+	users := generateUsers(5000, 1)
+	byID := make(map[int32]*pb.User, len(users))
+	for _, user := range users {
+		byID[user.Id] = user
+	}
+	idx := newUserIndex(byID)
+	phone := func(i int) string { return strconv.FormatInt(users[i].Phone, 10) }
+
+	tests := []struct {
+		name    string
+		query   Query
+		indexed bool // plan uses the indexes rather than asking for a full scan
+		exact   bool // plan yields exactly the matching users, not a superset
+	}{
+		{"city", Query{Criteria: []*pb.SearchCriteria{criteria(utils.CITY, pb.Operator_EQ, "Chicago")}}, true, true},
+		{"unknown city", Query{Criteria: []*pb.SearchCriteria{criteria(utils.CITY, pb.Operator_EQ, "Nowhere")}}, true, true},
+		{"phone", Query{Criteria: []*pb.SearchCriteria{criteria(utils.PHONE, pb.Operator_EQ, phone(2500))}}, true, true},
+		{"fname AND married", Query{Criteria: []*pb.SearchCriteria{
+			criteria(utils.FIRSTNAME, pb.Operator_EQ, "Emily"),
+			criteria(utils.MARRIED, pb.Operator_EQ, "true"),
+		}}, true, true},
+		{"criteria AND filter", Query{
+			Criteria: []*pb.SearchCriteria{criteria(utils.MARRIED, pb.Operator_EQ, "false")},
+			Filter:   leaf(criteria(utils.CITY, pb.Operator_EQ, "Boston")),
+		}, true, true},
+		{"city OR city", Query{Filter: group(pb.FilterGroup_OR,
+			leaf(criteria(utils.CITY, pb.Operator_EQ, "Chicago")),
+			leaf(criteria(utils.CITY, pb.Operator_EQ, "Boston")),
+		)}, true, true},
+		{"OR of ANDs", Query{Filter: group(pb.FilterGroup_OR,
+			group(pb.FilterGroup_AND,
+				leaf(criteria(utils.CITY, pb.Operator_EQ, "Chicago")),
+				leaf(criteria(utils.MARRIED, pb.Operator_EQ, "true")),
+			),
+			leaf(criteria(utils.FIRSTNAME, pb.Operator_EQ, "Ava")),
+		)}, true, false},
+		{"OR with an unindexed branch", Query{Filter: group(pb.FilterGroup_OR,
+			leaf(criteria(utils.CITY, pb.Operator_EQ, "Chicago")),
+			leaf(criteria(utils.FIRSTNAME, pb.Operator_PREFIX, "Ja")),
+		)}, false, false},
+		{"indexed AND unindexed OR", Query{Filter: group(pb.FilterGroup_AND,
+			leaf(criteria(utils.MARRIED, pb.Operator_EQ, "true")),
+			group(pb.FilterGroup_OR,
+				leaf(criteria(utils.CITY, pb.Operator_EQ, "Chicago")),
+				leaf(criteria(utils.FIRSTNAME, pb.Operator_PREFIX, "Ja")),
+			),
+		)}, true, false},
+		{"NOT", Query{Filter: group(pb.FilterGroup_NOT,
+			leaf(criteria(utils.CITY, pb.Operator_EQ, "Chicago")),
+		)}, false, false},
+		{"AND NOT", Query{Filter: group(pb.FilterGroup_AND,
+			leaf(criteria(utils.CITY, pb.Operator_EQ, "Denver")),
+			group(pb.FilterGroup_NOT, leaf(criteria(utils.MARRIED, pb.Operator_EQ, "true"))),
+		)}, true, false},
+		{"NOT inside OR", Query{Filter: group(pb.FilterGroup_OR,
+			leaf(criteria(utils.CITY, pb.Operator_EQ, "Denver")),
+			group(pb.FilterGroup_NOT, leaf(criteria(utils.CITY, pb.Operator_EQ, "Miami"))),
+		)}, false, false},
+		{"city IN", Query{Criteria: []*pb.SearchCriteria{in(utils.CITY, "Miami", "Austin", "Nowhere")}}, true, true},
+		{"phone IN with duplicates", Query{Criteria: []*pb.SearchCriteria{in(utils.PHONE, phone(1), phone(7), phone(1))}}, true, true},
+		{"height IN", Query{Criteria: []*pb.SearchCriteria{in(utils.HEIGHT, "160.3", "170", "170")}}, true, true},
+		{"height equals", Query{Criteria: []*pb.SearchCriteria{criteria(utils.HEIGHT, pb.Operator_EQ, "182.4")}}, true, true},
+		{"height range", Query{Criteria: []*pb.SearchCriteria{
+			criteria(utils.HEIGHT, pb.Operator_GTE, "160"),
+			criteria(utils.HEIGHT, pb.Operator_LTE, "160.5"),
+		}}, true, true},
+		{"exclusive height range", Query{Criteria: []*pb.SearchCriteria{
+			criteria(utils.HEIGHT, pb.Operator_GT, "160"),
+			criteria(utils.HEIGHT, pb.Operator_LT, "160.5"),
+		}}, true, true},
+		{"single height range", Query{Criteria: []*pb.SearchCriteria{
+			criteria(utils.HEIGHT, pb.Operator_GTE, "170"),
+			criteria(utils.HEIGHT, pb.Operator_LTE, "170"),
+		}}, true, true},
+		{"empty height range", Query{Criteria: []*pb.SearchCriteria{
+			criteria(utils.HEIGHT, pb.Operator_GT, "170"),
+			criteria(utils.HEIGHT, pb.Operator_LTE, "170"),
+		}}, true, true},
+		{"inverted height range", Query{Criteria: []*pb.SearchCriteria{
+			criteria(utils.HEIGHT, pb.Operator_GT, "180"),
+			criteria(utils.HEIGHT, pb.Operator_LT, "170"),
+		}}, true, true},
+		{"open lower bound", Query{Criteria: []*pb.SearchCriteria{criteria(utils.HEIGHT, pb.Operator_LT, "152")}}, true, true},
+		{"open upper bound", Query{Criteria: []*pb.SearchCriteria{criteria(utils.HEIGHT, pb.Operator_GTE, "198.5")}}, true, true},
+		{"bounds below every height", Query{Criteria: []*pb.SearchCriteria{criteria(utils.HEIGHT, pb.Operator_LTE, "100")}}, true, true},
+		{"tightened bounds", Query{Criteria: []*pb.SearchCriteria{
+			criteria(utils.HEIGHT, pb.Operator_GT, "160"),
+			criteria(utils.HEIGHT, pb.Operator_GTE, "170"),
+			criteria(utils.HEIGHT, pb.Operator_LT, "190"),
+			criteria(utils.HEIGHT, pb.Operator_LTE, "180"),
+		}}, true, true},
+		{"bounds split across the filter", Query{
+			Criteria: []*pb.SearchCriteria{criteria(utils.HEIGHT, pb.Operator_GTE, "165")},
+			Filter: group(pb.FilterGroup_AND,
+				leaf(criteria(utils.HEIGHT, pb.Operator_LT, "175")),
+				leaf(criteria(utils.CITY, pb.Operator_EQ, "Seattle")),
+			),
+		}, true, true},
+		{"height ranges OR", Query{Filter: group(pb.FilterGroup_OR,
+			group(pb.FilterGroup_AND,
+				leaf(criteria(utils.HEIGHT, pb.Operator_GTE, "150")),
+				leaf(criteria(utils.HEIGHT, pb.Operator_LT, "152")),
+			),
+			leaf(criteria(utils.HEIGHT, pb.Operator_GT, "199")),
+		)}, true, true},
+		{"range AND NOT", Query{Filter: group(pb.FilterGroup_AND,
+			leaf(criteria(utils.HEIGHT, pb.Operator_GTE, "190")),
+			group(pb.FilterGroup_NOT, leaf(criteria(utils.HEIGHT, pb.Operator_GTE, "195"))),
+		)}, true, false},
+		{"unindexed", Query{Criteria: []*pb.SearchCriteria{criteria(utils.CITY, pb.Operator_CONTAINS, "an")}}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := compileQuery(tt.query)
+			if err != nil {
+				t.Fatalf("compileQuery: %v", err)
+			}
+			var want []int32
+			for _, user := range users {
+				if match(user) {
+					want = append(want, user.Id)
+				}
+			}
+
+			candidates, ok := idx.plan(tt.query, byID)
+			if ok != tt.indexed {
+				t.Fatalf("plan used the indexes: %v, want %v", ok, tt.indexed)
+			}
+			if !ok {
+				return
+			}
+			seen := make(map[int32]bool, len(candidates))
+			var got []int32
+			for _, id := range candidates {
+				if seen[id] {
+					t.Fatalf("plan yielded user %d twice", id)
+				}
+				seen[id] = true
+				if match(byID[id]) {
+					got = append(got, id)
+				}
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, want) {
+				t.Fatalf("plan found %d matching users, full scan %d", len(got), len(want))
+			}
+			if tt.exact && len(candidates) != len(want) {
+				t.Errorf("plan yielded %d candidates for %d matching users", len(candidates), len(want))
+			}
+		})
+	}
+}
+
+// benchQueries are the searches timed by the benchmarks, against users
+var benchQueries = []struct {
+	name  string
+	query func(users []*pb.User) Query
+}{
+	{"phone", func(users []*pb.User) Query {
+		return Query{Criteria: []*pb.SearchCriteria{criteria(utils.PHONE, pb.Operator_EQ, strconv.FormatInt(users[len(users)/2].Phone, 10))}}
+	}},
+	{"fname_married", func([]*pb.User) Query {
+		return Query{Criteria: []*pb.SearchCriteria{
+			criteria(utils.FIRSTNAME, pb.Operator_EQ, "Emily"),
+			criteria(utils.MARRIED, pb.Operator_EQ, "true"),
+		}}
+	}},
+	{"city", func([]*pb.User) Query {
+		return Query{Criteria: []*pb.SearchCriteria{criteria(utils.CITY, pb.Operator_EQ, "Chicago")}}
+	}},
+	{"height_range", func([]*pb.User) Query {
+		return Query{Criteria: []*pb.SearchCriteria{
+			criteria(utils.HEIGHT, pb.Operator_GTE, "160"),
+			criteria(utils.HEIGHT, pb.Operator_LTE, "160.5"),
+		}}
+	}},
+	{"height_range_city_or", func([]*pb.User) Query {
+		return Query{Filter: group(pb.FilterGroup_AND,
+			leaf(criteria(utils.HEIGHT, pb.Operator_GTE, "160")),
+			leaf(criteria(utils.HEIGHT, pb.Operator_LTE, "180")),
+			group(pb.FilterGroup_OR,
+				leaf(criteria(utils.CITY, pb.Operator_EQ, "Chicago")),
+				leaf(criteria(utils.CITY, pb.Operator_EQ, "Boston")),
+			),
+		)}
+	}},
+	{"fname_prefix_unindexed", func([]*pb.User) Query {
+		return Query{Criteria: []*pb.SearchCriteria{criteria(utils.FIRSTNAME, pb.Operator_PREFIX, "Ja")}}
+	}},
+}
+
+var (
+	benchOnce  sync.Once
+	benchData  []*pb.User
+	benchStore = map[bool]*Database{}
+)
+
+// benchDatabase returns the generated users in a store with or without indexes,
+// built once for every benchmark
+func benchDatabase(indexed bool) (*Database, []*pb.User) {
+	benchOnce.Do(func() {
+		benchData = generateUsers(*benchUsers, 1)
+		benchStore[false] = NewDatabaseFromUsers(benchData, false)
+		benchStore[true] = NewDatabaseFromUsers(benchData, true)
+	})
+	return benchStore[indexed], benchData
+}
+
+func benchmarkSearch(b *testing.B, indexed bool) {
+	db, users := benchDatabase(indexed)
+	ctx := context.Background()
+	for _, bq := range benchQueries {
+		q := bq.query(users)
+		b.Run(bq.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := db.SearchUsers(ctx, q); err != nil && err != ErrNoUsersFound {
+					b.Fatalf("SearchUsers: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkSearchIndexed times searches answered from the secondary indexes;
+// compare with BenchmarkSearchScan, e.g. make bench
+func BenchmarkSearchIndexed(b *testing.B) {
+	benchmarkSearch(b, true)
+}
+
+// BenchmarkSearchScan times the same searches as full scans
+func BenchmarkSearchScan(b *testing.B) {
+	benchmarkSearch(b, false)
+}
@@ -0,0 +1,290 @@
+package database
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/logger"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/utils"
+	"go.uber.org/zap"
+)
+
+// Load modes deciding how invalid records in the users file are handled
+const (
+	LoadStrict  = "strict"  // Any invalid record fails the whole load
+	LoadLenient = "lenient" // Invalid records are skipped and reported in the log
+)
+
+// Formats of the users file
+const (
+	FormatJSON   = "json"   // A single JSON array of user objects
+	FormatNDJSON = "ndjson" // One JSON user object per line
+	FormatCSV    = "csv"    // A header row naming the fields, then one user per row
+	FormatYAML   = "yaml"   // A YAML sequence of user mappings, optionally split across documents
+)
+
+// maxReportedRecords caps the record errors included in a LoadError message
+const maxReportedRecords = 10
+
+// LoadOptions configures how the users file is read
+type LoadOptions struct {
+	Mode   string // One of the Load* modes, LoadStrict when empty
+	Format string // One of the Format* constants, detected from the file extension when empty
+}
+
+// RecordError describes a single record of the users file that failed validation
+type RecordError struct {
+	Index  int    // Position of the record in the file, starting at 0
+	Line   int    // Line the record starts on, zero when the format doesn't track lines
+	Reason string // Why the record was rejected
+}
+
+func (e RecordError) String() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("record %d (line %d): %s", e.Index, e.Line, e.Reason)
+	}
+	return fmt.Sprintf("record %d: %s", e.Index, e.Reason)
+}
+
+// LoadError reports every invalid record of a users file loaded in strict mode
+type LoadError struct {
+	Path    string
+	Records []RecordError
+}
+
+func (e *LoadError) Error() string {
+	msgs := make([]string, 0, maxReportedRecords)
+	for i, r := range e.Records {
+		if i == maxReportedRecords {
+			msgs = append(msgs, fmt.Sprintf("and %d more", len(e.Records)-i))
+			break
+		}
+		msgs = append(msgs, r.String())
+	}
+	return fmt.Sprintf("%s has %d invalid records: %s", e.Path, len(e.Records), strings.Join(msgs, "; "))
+}
+
+// userRecord is the schema of a single user in the users file. Pointers tell
+// missing fields apart from zero values.
+type userRecord struct {
+	ID      *int64
+	Fname   *string
+	City    *string
+	Phone   *int64
+	Height  *float32
+	Married *bool
+}
+
+// recordField names a field of userRecord and where it is decoded into
+type recordField struct {
+	name   string
+	target interface{}
+}
+
+// fields lists the fields of the record in the order they are reported
+func (r *userRecord) fields() []recordField {
+	return []recordField{
+		{"id", &r.ID},
+		{utils.FIRSTNAME, &r.Fname},
+		{utils.CITY, &r.City},
+		{utils.PHONE, &r.Phone},
+		{utils.HEIGHT, &r.Height},
+		{utils.MARRIED, &r.Married},
+	}
+}
+
+// validate checks every field of the record and converts it to a User. Fields
+// in malformed already failed to decode and are not reported as missing.
+func (r *userRecord) validate(malformed map[string]bool) (*pb.User, []string) {
+	var reasons []string
+	required := func(name string) {
+		if !malformed[name] {
+			reasons = append(reasons, name+" is required")
+		}
+	}
+	switch {
+	case r.ID == nil:
+		required("id")
+	case *r.ID <= 0 || *r.ID > math.MaxInt32:
+		reasons = append(reasons, fmt.Sprintf("id %d is out of range", *r.ID))
+	}
+	if r.Fname == nil || strings.TrimSpace(*r.Fname) == "" {
+		required(utils.FIRSTNAME)
+	}
+	if r.City == nil || strings.TrimSpace(*r.City) == "" {
+		required(utils.CITY)
+	}
+	switch {
+	case r.Phone == nil:
+		required(utils.PHONE)
+	case *r.Phone <= 0:
+		reasons = append(reasons, "phone must be greater than 0")
+	}
+	switch {
+	case r.Height == nil:
+		required(utils.HEIGHT)
+	case *r.Height <= 0:
+		reasons = append(reasons, "height must be greater than 0")
+	}
+	if r.Married == nil {
+		required(utils.MARRIED)
+	}
+	if len(reasons) > 0 || len(malformed) > 0 {
+		return nil, reasons
+	}
+	return &pb.User{
+		Id:      int32(*r.ID),
+		Fname:   *r.Fname,
+		City:    *r.City,
+		Phone:   *r.Phone,
+		Height:  *r.Height,
+		Married: *r.Married,
+	}, nil
+}
+
+// rawRecord is a single record as read from the users file, before validation
+type rawRecord struct {
+	line    int                   // Line the record starts on, zero when unknown
+	fields  map[string]fieldValue // Values by field name
+	invalid string                // Set instead of fields when the record is not a mapping
+}
+
+// fieldValue is a single undecoded value of a rawRecord
+type fieldValue struct {
+	text   string                         // Value as written in the file, used in error messages
+	decode func(target interface{}) error // Decodes the value into a pointer to a userRecord field
+}
+
+// loadUsers reads the users stored in path, streaming records in the format
+// chosen by opts.Format. Every record is decoded into a typed struct and
+// validated; unknown fields and duplicate IDs are rejected. In strict mode any
+// invalid record fails the load with a LoadError listing all of them, in
+// lenient mode they are skipped.
+func loadUsers(path string, opts LoadOptions) ([]*pb.User, error) {
+	if opts.Mode != "" && opts.Mode != LoadStrict && opts.Mode != LoadLenient {
+		return nil, fmt.Errorf("unknown load mode %q", opts.Mode)
+	}
+	format, err := detectFormat(path, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		logger.Error("Failed to open users file", zap.Error(err))
+		return nil, fmt.Errorf("error reading users file: %v", err)
+	}
+	defer file.Close()
+
+	var users []*pb.User
+	firstSeen := make(map[int32]int)
+	var invalid []RecordError
+	index := 0
+	err = recordReaders[format](file, func(rec rawRecord) {
+		user, reasons := decodeRecord(rec)
+		if user != nil {
+			if first, ok := firstSeen[user.Id]; ok {
+				reasons = append(reasons, fmt.Sprintf("duplicate id %d, first seen in record %d", user.Id, first))
+			} else {
+				firstSeen[user.Id] = index
+			}
+		}
+		if len(reasons) > 0 {
+			invalid = append(invalid, RecordError{Index: index, Line: rec.line, Reason: strings.Join(reasons, ", ")})
+		} else {
+			users = append(users, user)
+		}
+		index++
+	})
+	if err != nil {
+		logger.Errorf("Failed to parse %s file %s: %v", format, path, err)
+		return nil, fmt.Errorf("error parsing %s file: %v", format, err)
+	}
+
+	if len(invalid) == 0 {
+		return users, nil
+	}
+	if opts.Mode != LoadLenient {
+		loadErr := &LoadError{Path: path, Records: invalid}
+		logger.Errorf("Rejected users file: %v", loadErr)
+		return nil, loadErr
+	}
+	for _, r := range invalid {
+		logger.Warnf("Skipping invalid user %s", r)
+	}
+	logger.Warnf("Loaded %d of %d records from %s, skipped %d invalid records",
+		len(users), index, path, len(invalid))
+	return users, nil
+}
+
+// detectFormat returns format, or the format matching the extension of path when format is empty
+func detectFormat(path, format string) (string, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".json":
+			format = FormatJSON
+		case ".ndjson", ".jsonl":
+			format = FormatNDJSON
+		case ".csv":
+			format = FormatCSV
+		case ".yaml", ".yml":
+			format = FormatYAML
+		default:
+			return "", fmt.Errorf("cannot detect format of %s, set it explicitly", path)
+		}
+	}
+	if _, ok := recordReaders[format]; !ok {
+		return "", fmt.Errorf("unknown users file format %q", format)
+	}
+	return format, nil
+}
+
+// decodeRecord decodes and validates a single record field by field, so every
+// problem of the record is reported rather than only the first
+func decodeRecord(rec rawRecord) (*pb.User, []string) {
+	if rec.invalid != "" {
+		return nil, []string{rec.invalid}
+	}
+
+	var record userRecord
+	var reasons []string
+	malformed := make(map[string]bool)
+	seen := make(map[string]bool, len(rec.fields))
+	for _, f := range record.fields() {
+		value, ok := rec.fields[f.name]
+		if !ok {
+			continue
+		}
+		seen[f.name] = true
+		if err := value.decode(f.target); err != nil {
+			reasons = append(reasons, fmt.Sprintf("%s has invalid value %s", f.name, value.text))
+			malformed[f.name] = true
+			// A failed decode may leave a partial value behind
+			reflect.ValueOf(f.target).Elem().SetZero()
+		}
+	}
+	unknown := make([]string, 0, len(rec.fields)-len(seen))
+	for name := range rec.fields {
+		if !seen[name] {
+			unknown = append(unknown, strconv.Quote(name))
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		reasons = append(reasons, "unknown field "+name)
+	}
+
+	user, invalid := record.validate(malformed)
+	reasons = append(reasons, invalid...)
+	if len(reasons) > 0 {
+		return nil, reasons
+	}
+	return user, nil
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeUsersFile writes content to a file called name in a temporary directory
+// and returns its path
+func writeUsersFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// invalidUsers holds valid users 1 and 4 among records that each break a rule
+// of the loader, one per line with a blank line 2
+const invalidUsers = `{"id": 1, "fname": "John", "city": "Oslo", "phone": 100, "height": 180.5, "married": true}
+
+{"id": 2, "fname": "Jane", "city": "Oslo", "phone": "x", "height": 160, "married": false, "age": 30, "email": "jane@example.com"}
+{"id": 1, "fname": "Johnny", "city": "Bergen", "phone": 200, "height": 170, "married": false}
+{"id": 3, "fname": " ", "city": "Oslo", "phone": -1, "height": 0}
+{"id": 0, "fname": "Zero", "city": "Oslo", "phone": 300, "height": 150, "married": true}
+{"id": 5, "fname": "Broken",
+[1, 2]
+{"id": 4, "fname": "Emily", "city": "Trondheim", "phone": 400, "height": 165, "married": false}
+`
+
+// invalidRecords are the records of invalidUsers a load reports
+var invalidRecords = []RecordError{
+	{Index: 1, Line: 3, Reason: `phone has invalid value "x", unknown field "age", unknown field "email"`},
+	{Index: 2, Line: 4, Reason: "duplicate id 1, first seen in record 0"},
+	{Index: 3, Line: 5, Reason: "fname is required, phone must be greater than 0, height must be greater than 0, married is required"},
+	{Index: 4, Line: 6, Reason: "id 0 is out of range"},
+	{Index: 5, Line: 7, Reason: "invalid JSON"},
+	{Index: 6, Line: 8, Reason: "record must be a JSON object"},
+}
+
+func TestLoadUsersStrictReportsEveryInvalidRecord(t *testing.T) {
+	path := writeUsersFile(t, "users.ndjson", invalidUsers)
+	for _, mode := range []string{"", LoadStrict} {
+		users, err := loadUsers(path, LoadOptions{Mode: mode})
+		var loadErr *LoadError
+		if !errors.As(err, &loadErr) {
+			t.Fatalf("mode %q: error = %v, want a LoadError", mode, err)
+		}
+		if users != nil {
+			t.Errorf("mode %q: loaded %d users along with the error", mode, len(users))
+		}
+		if loadErr.Path != path || !slices.Equal(loadErr.Records, invalidRecords) {
+			t.Errorf("mode %q: LoadError = %+v, want the records\n%+v", mode, loadErr, invalidRecords)
+		}
+	}
+}
+
+func TestLoadUsersLenientSkipsInvalidRecords(t *testing.T) {
+	users, err := loadUsers(writeUsersFile(t, "users.ndjson", invalidUsers), LoadOptions{Mode: LoadLenient})
+	if err != nil {
+		t.Fatalf("loadUsers: %v", err)
+	}
+	// The duplicate of user 1 is skipped, not the user seen first
+	if got := ids(users); !slices.Equal(got, []int32{1, 4}) {
+		t.Fatalf("loaded users %v, want [1 4]", got)
+	}
+	if users[0].Fname != "John" {
+		t.Errorf("user 1 is %q, want the first record with its id", users[0].Fname)
+	}
+}
+
+func TestLoadUsersRejectsUnknownMode(t *testing.T) {
+	if _, err := loadUsers(writeUsersFile(t, "users.json", "[]"), LoadOptions{Mode: "best-effort"}); err == nil {
+		t.Error("unknown load mode accepted")
+	}
+}
+
+func TestLoadErrorMessage(t *testing.T) {
+	var records []RecordError
+	for i := 0; i < maxReportedRecords+2; i++ {
+		records = append(records, RecordError{Index: i, Line: i + 1, Reason: "id is required"})
+	}
+	records[0].Line = 0
+	msg := (&LoadError{Path: "users.json", Records: records}).Error()
+
+	for _, want := range []string{
+		"users.json has 12 invalid records: ",
+		"record 0: id is required; record 1 (line 2): id is required",
+		fmt.Sprintf("record %d (line %d): id is required; and 2 more", maxReportedRecords-1, maxReportedRecords),
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message %q does not contain %q", msg, want)
+		}
+	}
+	if strings.Contains(msg, fmt.Sprintf("record %d ", maxReportedRecords)) {
+		t.Errorf("message %q lists more than %d records", msg, maxReportedRecords)
+	}
+}
@@ -0,0 +1,225 @@
+package database
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+
+	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/utils"
+	"google.golang.org/protobuf/proto"
+)
+
+// Page size limits applied to paginated RPCs
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+// scopeHashSize is the length of the query fingerprint embedded in page tokens
+const scopeHashSize = 8
+
+// PageRequest describes which slice of a result set to return
+type PageRequest struct {
+	PageSize  int32  // Maximum number of users to return, DefaultPageSize when zero
+	PageToken string // Token from a previous Page, empty for the first page
+	OrderBy   string // Comma-separated "field [asc|desc]" list; ties are broken by id
+	Scope     []byte // Identifies the query, tokens are only accepted for the same scope
+}
+
+// Page is one slice of a sorted result set
+type Page struct {
+	Users         []*pb.User
+	NextPageToken string // Empty on the last page
+	TotalSize     int32  // Number of users across all pages
+}
+
+// sortKey orders users by a single field
+type sortKey struct {
+	field string
+	desc  bool
+}
+
+// Paginate sorts users by req.OrderBy and returns the page following req.PageToken.
+// Tokens hold the sort key of the last returned user rather than an offset, so they
+// stay valid and never skip or repeat users when the data changes between requests.
+func Paginate(users []*pb.User, req PageRequest) (*Page, error) {
+	if req.PageSize < 0 {
+		return nil, invalidArgument("page_size", "must not be negative")
+	}
+	pageSize := int(req.PageSize)
+	if pageSize == 0 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+
+	keys, err := parseOrderBy(req.OrderBy)
+	if err != nil {
+		return nil, err
+	}
+	less := func(a, b *pb.User) bool { return compareUsers(a, b, keys) < 0 }
+
+	scope := scopeHash(req.Scope)
+	remaining := users
+	if req.PageToken != "" {
+		after, err := decodePageToken(req.PageToken, scope)
+		if err != nil {
+			return nil, err
+		}
+		remaining = make([]*pb.User, 0, len(users))
+		for _, user := range users {
+			if less(after, user) {
+				remaining = append(remaining, user)
+			}
+		}
+	} else {
+		remaining = append([]*pb.User(nil), users...)
+	}
+
+	sort.Slice(remaining, func(i, j int) bool { return less(remaining[i], remaining[j]) })
+
+	page := &Page{TotalSize: int32(len(users))}
+	if len(remaining) > pageSize {
+		page.Users = remaining[:pageSize]
+		page.NextPageToken = encodePageToken(page.Users[pageSize-1], keys, scope)
+	} else {
+		page.Users = remaining
+	}
+	return page, nil
+}
+
+// parseOrderBy parses "field [asc|desc], ..." and appends id as the final tie-breaker
+func parseOrderBy(orderBy string) ([]sortKey, error) {
+	var keys []sortKey
+	hasID := false
+	for _, part := range strings.Split(orderBy, ",") {
+		tokens := strings.Fields(part)
+		if len(tokens) == 0 {
+			if strings.TrimSpace(orderBy) != "" {
+				return nil, invalidArgument("order_by", "empty sort field in %q", orderBy)
+			}
+			continue
+		}
+		key := sortKey{field: tokens[0]}
+		switch {
+		case len(tokens) == 1:
+		case len(tokens) == 2 && strings.EqualFold(tokens[1], "asc"):
+		case len(tokens) == 2 && strings.EqualFold(tokens[1], "desc"):
+			key.desc = true
+		default:
+			return nil, invalidArgument("order_by", "invalid sort field %q, expected \"field [asc|desc]\"", strings.TrimSpace(part))
+		}
+		switch key.field {
+		case "id":
+			hasID = true
+		case utils.FIRSTNAME, utils.CITY, utils.PHONE, utils.HEIGHT, utils.MARRIED:
+		default:
+			return nil, invalidArgument("order_by", "unknown sort field %q", key.field)
+		}
+		keys = append(keys, key)
+	}
+	if !hasID {
+		keys = append(keys, sortKey{field: "id"})
+	}
+	return keys, nil
+}
+
+// compareUsers compares a and b by each sort key in turn
+func compareUsers(a, b *pb.User, keys []sortKey) int {
+	for _, key := range keys {
+		var c int
+		switch key.field {
+		case "id":
+			c = cmp.Compare(a.Id, b.Id)
+		case utils.FIRSTNAME:
+			c = cmp.Compare(a.Fname, b.Fname)
+		case utils.CITY:
+			c = cmp.Compare(a.City, b.City)
+		case utils.PHONE:
+			c = cmp.Compare(a.Phone, b.Phone)
+		case utils.HEIGHT:
+			c = cmp.Compare(a.Height, b.Height)
+		case utils.MARRIED:
+			c = compareBool(a.Married, b.Married)
+		}
+		if key.desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case !a:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// scopeHash fingerprints the query a token was issued for
+func scopeHash(scope []byte) []byte {
+	sum := sha256.Sum256(scope)
+	return sum[:scopeHashSize]
+}
+
+// encodePageToken encodes the sort key of the last user of a page together with the
+// query fingerprint. Only the sorted fields are kept so tokens reveal nothing else.
+func encodePageToken(last *pb.User, keys []sortKey, scope []byte) string {
+	key := &pb.User{}
+	for _, k := range keys {
+		switch k.field {
+		case "id":
+			key.Id = last.Id
+		case utils.FIRSTNAME:
+			key.Fname = last.Fname
+		case utils.CITY:
+			key.City = last.City
+		case utils.PHONE:
+			key.Phone = last.Phone
+		case utils.HEIGHT:
+			key.Height = last.Height
+		case utils.MARRIED:
+			key.Married = last.Married
+		}
+	}
+	data, _ := proto.Marshal(key)
+	return base64.RawURLEncoding.EncodeToString(append(append([]byte(nil), scope...), data...))
+}
+
+// decodePageToken returns the user a token continues after
+func decodePageToken(token string, scope []byte) (*pb.User, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(data) < scopeHashSize {
+		return nil, invalidArgument("page_token", "malformed page token")
+	}
+	if !bytes.Equal(data[:scopeHashSize], scope) {
+		return nil, invalidArgument("page_token", "page token was issued for a different request")
+	}
+	last := &pb.User{}
+	if err := proto.Unmarshal(data[scopeHashSize:], last); err != nil {
+		return nil, invalidArgument("page_token", "malformed page token")
+	}
+	return last, nil
+}
+
+// PageScope serializes the parts of a request that define its result set
+func PageScope(m proto.Message) []byte {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	if err != nil {
+		return []byte(fmt.Sprint(m))
+	}
+	return data
+}
@@ -0,0 +1,182 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
+)
+
+// pageUsers returns users 1 to n, with heights repeating every 4 users so that
+// sorting by height needs the id tie-breaker
+func pageUsers(n int) []*pb.User {
+	users := make([]*pb.User, n)
+	for i := range users {
+		id := int32(i + 1)
+		users[i] = &pb.User{Id: id, Fname: fmt.Sprintf("user%d", id), City: "Oslo", Phone: int64(id), Height: float32(150 + id%4*10)}
+	}
+	return users
+}
+
+func ids(users []*pb.User) []int32 {
+	out := make([]int32, len(users))
+	for i, u := range users {
+		out[i] = u.Id
+	}
+	return out
+}
+
+func assertInvalidArgument(t *testing.T, err error, field string) {
+	t.Helper()
+	var invalid *InvalidArgumentError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("error = %v, want an InvalidArgumentError", err)
+	}
+	if got := invalid.Violations[0].Field; got != field {
+		t.Errorf("violation on %q, want %q", got, field)
+	}
+}
+
+func TestPaginateWalksEveryPage(t *testing.T) {
+	users := pageUsers(10)
+	req := PageRequest{PageSize: 3, OrderBy: "height desc", Scope: []byte("search")}
+	var got []int32
+	for pages := 0; ; pages++ {
+		if pages > 4 {
+			t.Fatal("paging did not end")
+		}
+		page, err := Paginate(users, req)
+		if err != nil {
+			t.Fatalf("Paginate: %v", err)
+		}
+		if page.TotalSize != 10 {
+			t.Errorf("TotalSize = %d, want 10", page.TotalSize)
+		}
+		got = append(got, ids(page.Users)...)
+		if page.NextPageToken == "" {
+			break
+		}
+		req.PageToken = page.NextPageToken
+	}
+	// Heights 180 (ids 3, 7), 170 (2, 6, 10), 160 (1, 5, 9), 150 (4, 8), ties by id
+	want := []int32{3, 7, 2, 6, 10, 1, 5, 9, 4, 8}
+	if !slices.Equal(got, want) {
+		t.Errorf("pages = %v, want %v", got, want)
+	}
+}
+
+func TestPaginateRejectsTokenOfOtherScope(t *testing.T) {
+	users := pageUsers(10)
+	search := func(city, orderBy string) []byte {
+		return PageScope(&pb.SearchUsersRequest{
+			Criterias: []*pb.SearchCriteria{{FieldName: "city", FieldValue: city}},
+			OrderBy:   orderBy,
+		})
+	}
+	scope := search("Oslo", "")
+	first, err := Paginate(users, PageRequest{PageSize: 2, Scope: scope})
+	if err != nil {
+		t.Fatalf("Paginate: %v", err)
+	}
+	// The scope of an identical request matches
+	if _, err := Paginate(users, PageRequest{PageSize: 2, PageToken: first.NextPageToken, Scope: search("Oslo", "")}); err != nil {
+		t.Fatalf("token rejected for the same query: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		req  PageRequest
+	}{
+		{"different query", PageRequest{PageSize: 2, PageToken: first.NextPageToken, Scope: search("Bergen", "")}},
+		{"different order", PageRequest{PageSize: 2, PageToken: first.NextPageToken, Scope: search("Oslo", "height")}},
+		{"other RPC", PageRequest{PageSize: 2, PageToken: first.NextPageToken, Scope: PageScope(&pb.GetUsersByIDRequest{UserIds: []int32{1, 2, 3}})}},
+		{"no scope", PageRequest{PageSize: 2, PageToken: first.NextPageToken}},
+		{"malformed", PageRequest{PageSize: 2, PageToken: "not a token!", Scope: scope}},
+		{"truncated", PageRequest{PageSize: 2, PageToken: first.NextPageToken[:4], Scope: scope}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Paginate(users, tt.req)
+			assertInvalidArgument(t, err, "page_token")
+		})
+	}
+}
+
+func TestPaginateAfterInsertsAndDeletes(t *testing.T) {
+	users := pageUsers(10)
+	req := PageRequest{PageSize: 4, OrderBy: "height desc", Scope: []byte("all")}
+	first, err := Paginate(users, req)
+	if err != nil {
+		t.Fatalf("Paginate: %v", err)
+	}
+	if want := []int32{3, 7, 2, 6}; !slices.Equal(ids(first.Users), want) {
+		t.Fatalf("first page = %v, want %v", ids(first.Users), want)
+	}
+
+	// Between the pages user 2 of the first page and user 10 of the second are
+	// deleted, user 5 is updated, and users are inserted before and after the cursor
+	var changed []*pb.User
+	for _, u := range users {
+		switch u.Id {
+		case 2, 10:
+		case 5:
+			changed = append(changed, &pb.User{Id: 5, Fname: "updated", Height: u.Height})
+		default:
+			changed = append(changed, u)
+		}
+	}
+	changed = append(changed,
+		&pb.User{Id: 20, Height: 180}, // Sorts before the cursor (170, 6)
+		&pb.User{Id: 21, Height: 170}, // Sorts right after it
+	)
+
+	req.PageToken = first.NextPageToken
+	second, err := Paginate(changed, req)
+	if err != nil {
+		t.Fatalf("Paginate: %v", err)
+	}
+	if want := []int32{21, 1, 5, 9}; !slices.Equal(ids(second.Users), want) {
+		t.Errorf("second page = %v, want %v", ids(second.Users), want)
+	}
+	if second.Users[2].Fname != "updated" {
+		t.Errorf("second page holds a stale copy of user 5")
+	}
+	if second.TotalSize != int32(len(changed)) {
+		t.Errorf("TotalSize = %d, want %d", second.TotalSize, len(changed))
+	}
+
+	req.PageToken = second.NextPageToken
+	third, err := Paginate(changed, req)
+	if err != nil {
+		t.Fatalf("Paginate: %v", err)
+	}
+	if want := []int32{4, 8}; !slices.Equal(ids(third.Users), want) || third.NextPageToken != "" {
+		t.Errorf("last page = %v (next %q), want %v", ids(third.Users), third.NextPageToken, want)
+	}
+}
+
+func TestPaginateValidatesRequest(t *testing.T) {
+	users := pageUsers(3)
+	if _, err := Paginate(users, PageRequest{PageSize: -1}); err == nil {
+		t.Error("negative page_size accepted")
+	} else {
+		assertInvalidArgument(t, err, "page_size")
+	}
+	for _, orderBy := range []string{"phone sideways", "password", "height,", "fname asc desc"} {
+		_, err := Paginate(users, PageRequest{OrderBy: orderBy})
+		if err == nil {
+			t.Errorf("order_by %q accepted", orderBy)
+			continue
+		}
+		assertInvalidArgument(t, err, "order_by")
+	}
+	page, err := Paginate(pageUsers(MaxPageSize+5), PageRequest{PageSize: MaxPageSize + 1})
+	if err != nil {
+		t.Fatalf("Paginate: %v", err)
+	}
+	if len(page.Users) != MaxPageSize {
+		t.Errorf("page of %d users, want the maximum %d", len(page.Users), MaxPageSize)
+	}
+}
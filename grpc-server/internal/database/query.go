@@ -0,0 +1,253 @@
+package database
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/utils"
+)
+
+// maxFilterDepth bounds how deeply filter groups may be nested
+const maxFilterDepth = 16
+
+// Query selects the users a search returns
+type Query struct {
+	Criteria []*pb.SearchCriteria // Criteria that must all match
+	Filter   *pb.SearchFilter     // Optional filter tree, ANDed with Criteria
+}
+
+// criteriaCount returns the number of criteria in q, including those of the filter tree
+func (q Query) criteriaCount() int {
+	var count func(f *pb.SearchFilter) int
+	count = func(f *pb.SearchFilter) int {
+		if f == nil {
+			return 0
+		}
+		if f.GetCriteria() != nil {
+			return 1
+		}
+		n := 0
+		for _, child := range f.GetGroup().GetFilters() {
+			n += count(child)
+		}
+		return n
+	}
+	return len(q.Criteria) + count(q.Filter)
+}
+
+// predicate reports whether a user matches a compiled query
+type predicate func(user *pb.User) bool
+
+// compileQuery validates q and compiles it into a single predicate, parsing
+// every criteria value once up front instead of once per user
+func compileQuery(q Query) (predicate, error) {
+	c := &queryCompiler{}
+	preds := make([]predicate, 0, len(q.Criteria)+1)
+	for i, criteria := range q.Criteria {
+		preds = append(preds, c.criteria(criteria, fmt.Sprintf("criterias[%d]", i)))
+	}
+	if q.Filter != nil {
+		preds = append(preds, c.filter(q.Filter, "filter", 0))
+	}
+	if len(c.violations) > 0 {
+		return nil, &InvalidArgumentError{Violations: c.violations}
+	}
+	return allOf(preds), nil
+}
+
+// queryCompiler collects every violation found while compiling a query
+type queryCompiler struct {
+	violations []FieldViolation
+}
+
+// fail records a violation and returns a predicate that never matches
+func (c *queryCompiler) fail(field, format string, args ...interface{}) predicate {
+	c.violations = append(c.violations, FieldViolation{Field: field, Description: fmt.Sprintf(format, args...)})
+	return func(*pb.User) bool { return false }
+}
+
+// filter compiles a single criteria or a nested group
+func (c *queryCompiler) filter(f *pb.SearchFilter, path string, depth int) predicate {
+	switch node := f.GetFilter().(type) {
+	case *pb.SearchFilter_Criteria:
+		return c.criteria(node.Criteria, path+".criteria")
+	case *pb.SearchFilter_Group:
+		return c.group(node.Group, path+".group", depth+1)
+	default:
+		return c.fail(path, "either criteria or group must be set")
+	}
+}
+
+// group compiles an AND, OR or NOT group of filters
+func (c *queryCompiler) group(g *pb.FilterGroup, path string, depth int) predicate {
+	if depth > maxFilterDepth {
+		return c.fail(path, "filter groups nested deeper than %d", maxFilterDepth)
+	}
+	if len(g.GetFilters()) == 0 {
+		return c.fail(path+".filters", "group must contain at least one filter")
+	}
+	preds := make([]predicate, 0, len(g.GetFilters()))
+	for i, f := range g.GetFilters() {
+		preds = append(preds, c.filter(f, fmt.Sprintf("%s.filters[%d]", path, i), depth))
+	}
+
+	switch g.GetLogic() {
+	case pb.FilterGroup_AND:
+		return allOf(preds)
+	case pb.FilterGroup_OR:
+		return anyOf(preds)
+	case pb.FilterGroup_NOT:
+		all := allOf(preds)
+		return func(user *pb.User) bool { return !all(user) }
+	default:
+		return c.fail(path+".logic", "unsupported logic %v", g.GetLogic())
+	}
+}
+
+// criteria compiles a single field comparison
+func (c *queryCompiler) criteria(criteria *pb.SearchCriteria, path string) predicate {
+	if criteria == nil {
+		return c.fail(path, "criteria must be set")
+	}
+	values := []string{criteria.GetFieldValue()}
+	if criteria.GetOperator() == pb.Operator_IN {
+		values = criteria.GetFieldValues()
+		if len(values) == 0 {
+			return c.fail(path+".field_values", "IN requires at least one value")
+		}
+	}
+
+	switch criteria.GetFieldName() {
+	case utils.FIRSTNAME:
+		return compileString(c, criteria.GetOperator(), values, path, func(u *pb.User) string { return u.Fname })
+	case utils.CITY:
+		return compileString(c, criteria.GetOperator(), values, path, func(u *pb.User) string { return u.City })
+	case utils.PHONE:
+		return compileOrdered(c, criteria.GetOperator(), values, path, parseInt, func(u *pb.User) int64 { return u.Phone })
+	case utils.HEIGHT:
+		return compileOrdered(c, criteria.GetOperator(), values, path, parseFloat32, func(u *pb.User) float32 { return u.Height })
+	case utils.MARRIED:
+		return compileEquality(c, criteria.GetOperator(), values, path, strconv.ParseBool, func(u *pb.User) bool { return u.Married })
+	default:
+		return c.fail(path+".field_name", "unknown field %q", criteria.GetFieldName())
+	}
+}
+
+// compileString adds the string-only operators on top of the ordered ones
+func compileString(c *queryCompiler, op pb.Operator, values []string, path string, get func(*pb.User) string) predicate {
+	value := values[0]
+	switch op {
+	case pb.Operator_PREFIX:
+		return func(u *pb.User) bool { return strings.HasPrefix(get(u), value) }
+	case pb.Operator_CONTAINS:
+		return func(u *pb.User) bool { return strings.Contains(get(u), value) }
+	case pb.Operator_IEQ:
+		return func(u *pb.User) bool { return strings.EqualFold(get(u), value) }
+	case pb.Operator_REGEX:
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return c.fail(path+".field_value", "invalid regular expression: %v", err)
+		}
+		return func(u *pb.User) bool { return re.MatchString(get(u)) }
+	default:
+		return compileOrdered(c, op, values, path, parseString, get)
+	}
+}
+
+// compileOrdered handles the comparison operators for ordered field types
+func compileOrdered[T cmp.Ordered](c *queryCompiler, op pb.Operator, values []string, path string, parse func(string) (T, error), get func(*pb.User) T) predicate {
+	var cmpFn func(a, b T) bool
+	switch op {
+	case pb.Operator_LT:
+		cmpFn = func(a, b T) bool { return a < b }
+	case pb.Operator_LTE:
+		cmpFn = func(a, b T) bool { return a <= b }
+	case pb.Operator_GT:
+		cmpFn = func(a, b T) bool { return a > b }
+	case pb.Operator_GTE:
+		cmpFn = func(a, b T) bool { return a >= b }
+	default:
+		return compileEquality(c, op, values, path, parse, get)
+	}
+
+	want, err := parse(values[0])
+	if err != nil {
+		return c.fail(path+".field_value", "invalid value %q", values[0])
+	}
+	return func(u *pb.User) bool { return cmpFn(get(u), want) }
+}
+
+// compileEquality handles EQ, NE and IN for comparable field types
+func compileEquality[T comparable](c *queryCompiler, op pb.Operator, values []string, path string, parse func(string) (T, error), get func(*pb.User) T) predicate {
+	field := path + ".field_value"
+	if op == pb.Operator_IN {
+		field = path + ".field_values"
+	}
+	set := make(map[T]struct{}, len(values))
+	for _, v := range values {
+		parsed, err := parse(v)
+		if err != nil {
+			return c.fail(field, "invalid value %q", v)
+		}
+		set[parsed] = struct{}{}
+	}
+
+	switch op {
+	case pb.Operator_EQ, pb.Operator_IN:
+		return func(u *pb.User) bool {
+			_, ok := set[get(u)]
+			return ok
+		}
+	case pb.Operator_NE:
+		return func(u *pb.User) bool {
+			_, ok := set[get(u)]
+			return !ok
+		}
+	default:
+		return c.fail(path+".operator", "operator %v is not supported for this field", op)
+	}
+}
+
+// allOf matches when every predicate matches
+func allOf(preds []predicate) predicate {
+	if len(preds) == 1 {
+		return preds[0]
+	}
+	return func(user *pb.User) bool {
+		for _, p := range preds {
+			if !p(user) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// anyOf matches when at least one predicate matches
+func anyOf(preds []predicate) predicate {
+	return func(user *pb.User) bool {
+		for _, p := range preds {
+			if p(user) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func parseString(s string) (string, error) {
+	return s, nil
+}
+
+func parseInt(s string) (int64, error) {
+	return strconv.ParseInt(s, 10, 64)
+}
+
+func parseFloat32(s string) (float32, error) {
+	f, err := strconv.ParseFloat(s, 32)
+	return float32(f), err
+}
@@ -0,0 +1,138 @@
+package database
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/utils"
+)
+
+// queryUsers are the users the compiled queries are matched against
+var queryUsers = []*pb.User{
+	{Id: 1, Fname: "John", City: "Oslo", Phone: 100, Height: 150.5, Married: true},
+	{Id: 2, Fname: "Jane", City: "Bergen", Phone: 200, Height: 160, Married: false},
+	{Id: 3, Fname: "Johnny", City: "Oslo", Phone: 300, Height: 170.25, Married: true},
+	{Id: 4, Fname: "jane", City: "Trondheim", Phone: 400, Height: 180, Married: false},
+	{Id: 5, Fname: "Emily", City: "New Oslo", Phone: 500, Height: 160, Married: true},
+}
+
+// nest wraps f in depth AND groups
+func nest(depth int, f *pb.SearchFilter) *pb.SearchFilter {
+	for i := 0; i < depth; i++ {
+		f = group(pb.FilterGroup_AND, f)
+	}
+	return f
+}
+
+func TestCompileQueryMatches(t *testing.T) {
+	married := criteria(utils.MARRIED, pb.Operator_EQ, "true")
+	tests := []struct {
+		name  string
+		query Query
+		want  []int32
+	}{
+		{"height LT", Query{Criteria: []*pb.SearchCriteria{criteria(utils.HEIGHT, pb.Operator_LT, "160")}}, []int32{1}},
+		{"height LTE", Query{Criteria: []*pb.SearchCriteria{criteria(utils.HEIGHT, pb.Operator_LTE, "160")}}, []int32{1, 2, 5}},
+		{"height GT", Query{Criteria: []*pb.SearchCriteria{criteria(utils.HEIGHT, pb.Operator_GT, "170.25")}}, []int32{4}},
+		{"height GTE", Query{Criteria: []*pb.SearchCriteria{criteria(utils.HEIGHT, pb.Operator_GTE, "170.25")}}, []int32{3, 4}},
+		{"height EQ", Query{Criteria: []*pb.SearchCriteria{criteria(utils.HEIGHT, pb.Operator_EQ, "160")}}, []int32{2, 5}},
+		{"phone LT", Query{Criteria: []*pb.SearchCriteria{criteria(utils.PHONE, pb.Operator_LT, "300")}}, []int32{1, 2}},
+		{"phone NE", Query{Criteria: []*pb.SearchCriteria{criteria(utils.PHONE, pb.Operator_NE, "300")}}, []int32{1, 2, 4, 5}},
+		{"phone IN", Query{Criteria: []*pb.SearchCriteria{in(utils.PHONE, "100", "400", "999")}}, []int32{1, 4}},
+		{"fname EQ is case sensitive", Query{Criteria: []*pb.SearchCriteria{criteria(utils.FIRSTNAME, pb.Operator_EQ, "Jane")}}, []int32{2}},
+		{"fname IEQ", Query{Criteria: []*pb.SearchCriteria{criteria(utils.FIRSTNAME, pb.Operator_IEQ, "JANE")}}, []int32{2, 4}},
+		{"fname PREFIX", Query{Criteria: []*pb.SearchCriteria{criteria(utils.FIRSTNAME, pb.Operator_PREFIX, "John")}}, []int32{1, 3}},
+		{"fname GT compares bytes", Query{Criteria: []*pb.SearchCriteria{criteria(utils.FIRSTNAME, pb.Operator_GT, "Jane")}}, []int32{1, 3, 4}},
+		{"fname REGEX", Query{Criteria: []*pb.SearchCriteria{criteria(utils.FIRSTNAME, pb.Operator_REGEX, "^J(ohn|ane)$")}}, []int32{1, 2}},
+		{"city CONTAINS", Query{Criteria: []*pb.SearchCriteria{criteria(utils.CITY, pb.Operator_CONTAINS, "Oslo")}}, []int32{1, 3, 5}},
+		{"city IN", Query{Criteria: []*pb.SearchCriteria{in(utils.CITY, "Oslo", "Bergen")}}, []int32{1, 2, 3}},
+		{"married EQ", Query{Criteria: []*pb.SearchCriteria{married}}, []int32{1, 3, 5}},
+		{"married NE", Query{Criteria: []*pb.SearchCriteria{criteria(utils.MARRIED, pb.Operator_NE, "true")}}, []int32{2, 4}},
+		{"married IN", Query{Criteria: []*pb.SearchCriteria{in(utils.MARRIED, "false")}}, []int32{2, 4}},
+		{"criteria are ANDed", Query{Criteria: []*pb.SearchCriteria{criteria(utils.CITY, pb.Operator_EQ, "Oslo"), criteria(utils.HEIGHT, pb.Operator_GT, "160")}}, []int32{3}},
+		{"OR group", Query{Filter: group(pb.FilterGroup_OR,
+			leaf(criteria(utils.HEIGHT, pb.Operator_LT, "155")),
+			leaf(criteria(utils.CITY, pb.Operator_EQ, "Bergen")),
+		)}, []int32{1, 2}},
+		{"NOT group", Query{Filter: group(pb.FilterGroup_NOT, leaf(criteria(utils.CITY, pb.Operator_CONTAINS, "Oslo")))}, []int32{2, 4}},
+		{"NOT of several filters negates their AND", Query{Filter: group(pb.FilterGroup_NOT,
+			leaf(criteria(utils.CITY, pb.Operator_EQ, "Oslo")),
+			leaf(married),
+		)}, []int32{2, 4, 5}},
+		{"filter ANDed with criteria", Query{
+			Criteria: []*pb.SearchCriteria{married},
+			Filter:   leaf(criteria(utils.FIRSTNAME, pb.Operator_PREFIX, "J")),
+		}, []int32{1, 3}},
+		{"groups nested as deep as allowed", Query{Filter: nest(maxFilterDepth, leaf(married))}, []int32{1, 3, 5}},
+		{"no criteria", Query{}, []int32{1, 2, 3, 4, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := compileQuery(tt.query)
+			if err != nil {
+				t.Fatalf("compileQuery: %v", err)
+			}
+			var got []int32
+			for _, u := range queryUsers {
+				if match(u) {
+					got = append(got, u.Id)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("matched %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileQueryRejects(t *testing.T) {
+	// The innermost group of one nested a level too deep
+	tooDeep := "filter" + strings.Repeat(".group.filters[0]", maxFilterDepth) + ".group"
+	tests := []struct {
+		name   string
+		query  Query
+		fields []string // Paths of the expected violations, in order
+	}{
+		{"unknown field", Query{Criteria: []*pb.SearchCriteria{criteria("age", pb.Operator_EQ, "30")}}, []string{"criterias[0].field_name"}},
+		{"height that is not a number", Query{Criteria: []*pb.SearchCriteria{criteria(utils.HEIGHT, pb.Operator_GT, "abc")}}, []string{"criterias[0].field_value"}},
+		{"phone that is not an integer", Query{Criteria: []*pb.SearchCriteria{criteria(utils.PHONE, pb.Operator_EQ, "1.5")}}, []string{"criterias[0].field_value"}},
+		{"married that is not a boolean", Query{Criteria: []*pb.SearchCriteria{criteria(utils.MARRIED, pb.Operator_EQ, "maybe")}}, []string{"criterias[0].field_value"}},
+		{"IN with an invalid value", Query{Criteria: []*pb.SearchCriteria{in(utils.PHONE, "100", "x")}}, []string{"criterias[0].field_values"}},
+		{"IN without values", Query{Criteria: []*pb.SearchCriteria{in(utils.CITY)}}, []string{"criterias[0].field_values"}},
+		{"invalid regular expression", Query{Criteria: []*pb.SearchCriteria{criteria(utils.CITY, pb.Operator_REGEX, "(")}}, []string{"criterias[0].field_value"}},
+		{"string operator on a number", Query{Criteria: []*pb.SearchCriteria{criteria(utils.HEIGHT, pb.Operator_PREFIX, "1")}}, []string{"criterias[0].operator"}},
+		{"ordering of booleans", Query{Criteria: []*pb.SearchCriteria{criteria(utils.MARRIED, pb.Operator_LT, "true")}}, []string{"criterias[0].operator"}},
+		{"missing criteria", Query{Criteria: []*pb.SearchCriteria{nil}}, []string{"criterias[0]"}},
+		{"empty filter", Query{Filter: &pb.SearchFilter{}}, []string{"filter"}},
+		{"empty group", Query{Filter: group(pb.FilterGroup_OR)}, []string{"filter.group.filters"}},
+		{"unsupported logic", Query{Filter: group(pb.FilterGroup_Logic(99), leaf(criteria(utils.CITY, pb.Operator_EQ, "Oslo")))}, []string{"filter.group.logic"}},
+		{"invalid criteria in a group", Query{Filter: group(pb.FilterGroup_AND,
+			leaf(criteria(utils.CITY, pb.Operator_EQ, "Oslo")),
+			leaf(criteria(utils.HEIGHT, pb.Operator_EQ, "tall")),
+		)}, []string{"filter.group.filters[1].criteria.field_value"}},
+		{"groups nested too deep", Query{Filter: nest(maxFilterDepth+1, leaf(criteria(utils.CITY, pb.Operator_EQ, "Oslo")))}, []string{tooDeep}},
+		{"every violation is reported", Query{
+			Criteria: []*pb.SearchCriteria{criteria("age", pb.Operator_EQ, "30"), criteria(utils.HEIGHT, pb.Operator_LT, "short")},
+			Filter:   leaf(criteria(utils.MARRIED, pb.Operator_EQ, "maybe")),
+		}, []string{"criterias[0].field_name", "criterias[1].field_value", "filter.criteria.field_value"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compileQuery(tt.query)
+			var invalid *InvalidArgumentError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("error = %v, want an InvalidArgumentError", err)
+			}
+			var fields []string
+			for _, v := range invalid.Violations {
+				fields = append(fields, v.Field)
+			}
+			if !slices.Equal(fields, tt.fields) {
+				t.Errorf("violations on %q, want %q", fields, tt.fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/logger"
+)
+
+// ReloadStatus reports the outcome of the JSON file reloads
+type ReloadStatus struct {
+	Reloads       uint64    // Successful reloads since startup
+	Failures      uint64    // Failed reload attempts since startup
+	LastReload    time.Time // Time of the last successful reload, zero if none
+	LastError     string    // Error of the last failed attempt, empty once a reload succeeds
+	LastErrorTime time.Time // Time of the last failed attempt
+}
+
+// reloader tracks the watched file and the reload counters
+type reloader struct {
+	mu     sync.Mutex
+	status ReloadStatus
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// fileVersion identifies a version of the watched file
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watch polls jsonPath every interval and atomically swaps in a new snapshot
+// when the file changes. Users created through the API are replaced by the
+// file contents on reload. Watching stops when Close is called.
+func (d *Database) Watch(jsonPath string, interval time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.reload.cancel = cancel
+	d.reload.done = make(chan struct{})
+
+	last, _ := statVersion(jsonPath)
+	logger.Infof("Watching %s for changes every %v", jsonPath, interval)
+	go func() {
+		defer close(d.reload.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			current, err := statVersion(jsonPath)
+			if err != nil {
+				d.recordReload(fmt.Errorf("error checking JSON file: %v", err))
+				continue
+			}
+			if current == last {
+				continue
+			}
+			last = current
+			d.recordReload(d.Reload(jsonPath))
+		}
+	}()
+}
+
+// Reload parses and validates jsonPath in the background of running requests,
+// then swaps the new users and indexes in under the write lock. Searches in
+// flight finish on the snapshot they started with.
+func (d *Database) Reload(jsonPath string) error {
+	users, err := loadUsers(jsonPath, d.load)
+	if err != nil {
+		return err
+	}
+	d.mu.RLock()
+	indexed := d.index != nil
+	d.mu.RUnlock()
+	next := NewDatabaseFromUsers(users, indexed)
+
+	d.mu.Lock()
+	d.users, d.index, d.nextID = next.users, next.index, next.nextID
+	d.mu.Unlock()
+	logger.Infof("Reloaded %v users from %s", len(users), jsonPath)
+	return nil
+}
+
+// ReloadStatus returns the reload counters and the last reload error
+func (d *Database) ReloadStatus() ReloadStatus {
+	d.reload.mu.Lock()
+	defer d.reload.mu.Unlock()
+	return d.reload.status
+}
+
+// recordReload updates the reload counters with the outcome of an attempt
+func (d *Database) recordReload(err error) {
+	d.reload.mu.Lock()
+	defer d.reload.mu.Unlock()
+	now := time.Now()
+	if err != nil {
+		d.reload.status.Failures++
+		d.reload.status.LastError = err.Error()
+		d.reload.status.LastErrorTime = now
+		logger.Errorf("Failed to reload JSON file, keeping current data: %v", err)
+		return
+	}
+	d.reload.status.Reloads++
+	d.reload.status.LastReload = now
+	d.reload.status.LastError = ""
+}
+
+// stopWatching stops the watcher started by Watch and waits for it to exit
+func (d *Database) stopWatching() {
+	if d.reload.cancel != nil {
+		d.reload.cancel()
+		<-d.reload.done
+	}
+}
+
+func statVersion(path string) (fileVersion, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileVersion{}, err
+	}
+	return fileVersion{modTime: info.ModTime(), size: info.Size()}, nil
+}
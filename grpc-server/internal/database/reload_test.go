@@ -0,0 +1,174 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
+)
+
+// watchInterval is how often the tests poll the watched file
+const watchInterval = 5 * time.Millisecond
+
+// rewrite replaces the content of path, moving its modification time forward
+// so the change is seen even when the size and the clock granularity are equal
+func rewrite(t *testing.T, path, content string) {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := info.ModTime().Add(time.Second)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitForStatus polls the reload status of db until done accepts it
+func waitForStatus(t *testing.T, db *Database, done func(ReloadStatus) bool) ReloadStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status := db.ReloadStatus()
+		if done(status) {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("reload status is still %+v", status)
+		}
+		time.Sleep(watchInterval)
+	}
+}
+
+// watchedDatabase returns a database loaded from a file holding seedUsers,
+// watching it for changes until the test ends
+func watchedDatabase(t *testing.T) (*Database, string) {
+	t.Helper()
+	path := writeUsersFile(t, "users.json", seedUsers)
+	db, err := NewDatabase(path, LoadOptions{})
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	db.Watch(path, watchInterval)
+	t.Cleanup(func() { db.Close() })
+	return db, path
+}
+
+func TestWatchSwapsInRewrittenFile(t *testing.T) {
+	db, path := watchedDatabase(t)
+	ctx := context.Background()
+	started := time.Now()
+
+	rewrite(t, path, `[
+  {"id": 1, "fname": "Johnny", "city": "New York", "phone": 1234567890, "height": 180.5, "married": true},
+  {"id": 3, "fname": "Emily", "city": "Chicago", "phone": 3456789012, "height": 170.2, "married": false}
+]`)
+	status := waitForStatus(t, db, func(s ReloadStatus) bool { return s.Reloads > 0 })
+	if status.Reloads != 1 || status.Failures != 0 || status.LastError != "" || status.LastReload.Before(started) {
+		t.Errorf("reload status = %+v, want a single successful reload", status)
+	}
+
+	if user, err := db.GetUserByID(ctx, 1); err != nil || user.Fname != "Johnny" {
+		t.Errorf("user 1 = %v, %v; want the rewritten user", user, err)
+	}
+	if _, err := db.GetUserByID(ctx, 2); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("user 2 removed from the file: error = %v, want ErrUserNotFound", err)
+	}
+	// The indexes are rebuilt along with the users
+	users, err := db.SearchUsers(ctx, Query{Criteria: []*pb.SearchCriteria{criteria("city", pb.Operator_EQ, "Chicago")}})
+	if err != nil || len(users) != 1 || users[0].Id != 3 {
+		t.Errorf("search of the new city = %v, %v; want user 3", users, err)
+	}
+	// IDs of created users continue after those of the file
+	created, err := db.CreateUser(ctx, &pb.User{Fname: "Ann", City: "Oslo", Phone: 1, Height: 160})
+	if err != nil || created.Id != 4 {
+		t.Errorf("created user = %v, %v; want id 4", created, err)
+	}
+}
+
+func TestWatchKeepsSnapshotOfInvalidFile(t *testing.T) {
+	db, path := watchedDatabase(t)
+	ctx := context.Background()
+
+	rewrite(t, path, `[{"id": 1, "fname": "John"}, {"id": 9, "fname": "Broken"`)
+	status := waitForStatus(t, db, func(s ReloadStatus) bool { return s.Failures > 0 })
+	if status.Reloads != 0 || status.LastError == "" || status.LastErrorTime.IsZero() {
+		t.Errorf("reload status = %+v, want a failure", status)
+	}
+	if n, _ := db.CountUsers(ctx); n != 2 {
+		t.Errorf("store holds %d users after a failed reload, want the 2 it had", n)
+	}
+	if user, err := db.GetUserByID(ctx, 1); err != nil || user.City != "New York" {
+		t.Errorf("user 1 = %v, %v; want it unchanged", user, err)
+	}
+
+	// A file failing validation is rejected alike, reporting its records
+	rewrite(t, path, `[{"id": 1, "fname": "John", "city": "Oslo", "phone": 1, "height": 1}]`)
+	status = waitForStatus(t, db, func(s ReloadStatus) bool { return s.Failures > 1 })
+	if !strings.Contains(status.LastError, "married is required") {
+		t.Errorf("last error = %q, want the invalid record", status.LastError)
+	}
+
+	// A missing file is a failure too
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	status = waitForStatus(t, db, func(s ReloadStatus) bool { return strings.Contains(s.LastError, "error checking JSON file") })
+	if n, _ := db.CountUsers(ctx); n != 2 {
+		t.Errorf("store holds %d users without its file, want the 2 it had", n)
+	}
+
+	// The next valid version is loaded and clears the error
+	if err := os.WriteFile(path, []byte(`[{"id": 7, "fname": "Ann", "city": "Oslo", "phone": 1, "height": 1, "married": false}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	status = waitForStatus(t, db, func(s ReloadStatus) bool { return s.Reloads > 0 })
+	if status.LastError != "" || status.Failures < 3 {
+		t.Errorf("reload status = %+v, want the error cleared and the failures kept", status)
+	}
+	if n, _ := db.CountUsers(ctx); n != 1 {
+		t.Errorf("store holds %d users, want the user of the fixed file", n)
+	}
+}
+
+func TestCloseStopsWatching(t *testing.T) {
+	path := writeUsersFile(t, "users.json", seedUsers)
+	db, err := NewDatabase(path, LoadOptions{})
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	db.Watch(path, watchInterval)
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	select {
+	case <-db.reload.done:
+	default:
+		t.Fatal("the watcher is still running after Close")
+	}
+
+	rewrite(t, path, "[]")
+	time.Sleep(10 * watchInterval)
+	if status := db.ReloadStatus(); status != (ReloadStatus{}) {
+		t.Errorf("reload status = %+v after Close, want no attempts", status)
+	}
+	if n, _ := db.CountUsers(context.Background()); n != 2 {
+		t.Errorf("store holds %d users, want those loaded before Close", n)
+	}
+
+	// Closing a store that never watched its file is fine
+	unwatched, err := NewDatabase(path, LoadOptions{})
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	if err := unwatched.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
+)
+
+// Storage backends selectable at startup
+const (
+	BackendMemory = "memory" // JSON file loaded into an in-memory map
+	BackendBolt   = "bolt"   // Durable bbolt file seeded from the JSON file on first start
+)
+
+// StoreConfig selects and configures the storage backend
+type StoreConfig struct {
+	Backend  string      // One of the Backend* constants
+	JSONPath string      // Users file the users are loaded or seeded from, see LoadOptions.Format
+	BoltPath string      // Location of the bbolt file for BackendBolt
+	Load     LoadOptions // How the users file is parsed and validated
+	// ReloadInterval is how often BackendMemory checks JSONPath for changes,
+	// zero disables reloading
+	ReloadInterval time.Duration
+}
+
+// UserStore is the storage backend UserService reads and writes users through
+type UserStore interface {
+	// GetUserByID retrieves a single user by ID
+	GetUserByID(ctx context.Context, id int32) (*pb.User, error)
+	// GetUsersByID retrieves the users matching ids, skipping unknown IDs
+	GetUsersByID(ctx context.Context, ids []int32) ([]*pb.User, error)
+	// SearchUsers returns every user matching the query
+	SearchUsers(ctx context.Context, q Query) ([]*pb.User, error)
+	// ScanUsers calls fn for each user matching the query as it is found, stopping
+	// at the first error returned by fn or when ctx is done
+	ScanUsers(ctx context.Context, q Query, fn func(*pb.User) error) error
+	// CreateUser stores a new user, assigning an ID when user.Id is zero
+	CreateUser(ctx context.Context, user *pb.User) (*pb.User, error)
+	// UpdateUser overwrites the fields named in paths, or all fields when paths is empty
+	UpdateUser(ctx context.Context, user *pb.User, paths []string) (*pb.User, error)
+	// DeleteUser removes a user by ID
+	DeleteUser(ctx context.Context, id int32) error
+	// CountUsers returns the number of stored users
+	CountUsers(ctx context.Context) (int, error)
+	// Close releases any resources held by the backend
+	Close() error
+}
+
+var _ UserStore = (*Database)(nil)
+
+// NewUserStore creates the storage backend selected by cfg.Backend
+func NewUserStore(cfg StoreConfig) (UserStore, error) {
+	switch cfg.Backend {
+	case "", BackendMemory:
+		db, err := NewDatabase(cfg.JSONPath, cfg.Load)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.ReloadInterval > 0 {
+			db.Watch(cfg.JSONPath, cfg.ReloadInterval)
+		}
+		return db, nil
+	case BackendBolt:
+		store, err := NewBoltStore(cfg.BoltPath, cfg.JSONPath, cfg.Load)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
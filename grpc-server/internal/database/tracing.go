@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/ParasJain0307/grpc-project/grpc-server/internal/database")
+
+// Span attributes of searches
+const (
+	attrBackend       = "users.store.backend"
+	attrCriteriaCount = "users.search.criteria_count"
+	attrResultCount   = "users.search.result_count"
+)
+
+// traceSearch runs search in a span recording the number of criteria and results
+func traceSearch(ctx context.Context, name, backend string, q Query, search func() ([]*pb.User, error)) ([]*pb.User, error) {
+	_, span := startSearchSpan(ctx, name, backend, q)
+	defer span.End()
+	users, err := search()
+	endSearchSpan(span, len(users), err)
+	return users, err
+}
+
+// traceScan runs scan in a span recording the number of criteria and of users passed to fn
+func traceScan(ctx context.Context, name, backend string, q Query, fn func(*pb.User) error, scan func(ctx context.Context, fn func(*pb.User) error) error) error {
+	ctx, span := startSearchSpan(ctx, name, backend, q)
+	defer span.End()
+	results := 0
+	err := scan(ctx, func(user *pb.User) error {
+		results++
+		return fn(user)
+	})
+	endSearchSpan(span, results, err)
+	return err
+}
+
+func startSearchSpan(ctx context.Context, name, backend string, q Query) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(
+		attribute.String(attrBackend, backend),
+		attribute.Int(attrCriteriaCount, q.criteriaCount()),
+	))
+}
+
+func endSearchSpan(span trace.Span, results int, err error) {
+	span.SetAttributes(attribute.Int(attrResultCount, results))
+	// An empty result is an expected outcome, not a failure of the store
+	if err != nil && !errors.Is(err, ErrNoUsersFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,212 @@
+// Package lifecycle runs the servers of the service and shuts them down in order,
+// giving load balancers time to stop routing traffic and in-flight calls time to
+// complete before anything is forcibly closed.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/logger"
+	"github.com/soheilhy/cmux"
+	"google.golang.org/grpc"
+)
+
+// ErrDrainTimeout reports that a component was stopped before its in-flight work completed
+var ErrDrainTimeout = errors.New("drain timeout exceeded")
+
+// Component is a server run by a Manager
+type Component struct {
+	Name string
+	// Serve runs the component until it is shut down or stopped, after which it returns nil
+	Serve func() error
+	// Shutdown stops accepting work and waits for the work in flight until ctx is done
+	Shutdown func(ctx context.Context) error
+	// Stop aborts the work Shutdown did not complete in time
+	Stop func()
+}
+
+// Options configures a Manager
+type Options struct {
+	// OnShutdown is called as soon as shutdown starts, e.g. to fail readiness checks
+	OnShutdown func()
+	// PreStopDelay keeps serving for a while after OnShutdown, so load balancers
+	// stop routing new traffic before the listeners close
+	PreStopDelay time.Duration
+	// DrainTimeout bounds the shutdown of all components, after which the ones
+	// still draining are stopped
+	DrainTimeout time.Duration
+}
+
+// Manager starts components together and shuts them down in the reverse order
+// they were added
+type Manager struct {
+	opts       Options
+	components []Component
+}
+
+// New returns a Manager without components
+func New(opts Options) *Manager {
+	return &Manager{opts: opts}
+}
+
+// Add registers c. A component calling another one, such as the gateway calling
+// the gRPC server, must be added after it so it is shut down first.
+func (m *Manager) Add(c Component) {
+	m.components = append(m.components, c)
+}
+
+// Run serves every component until ctx is done or one of them fails, then shuts
+// them all down. It returns nil after a clean shutdown, the first failure of a
+// component, or ErrDrainTimeout when a component had to be stopped.
+func (m *Manager) Run(ctx context.Context) error {
+	failures := make(chan error, len(m.components))
+	for _, c := range m.components {
+		go func(c Component) {
+			if err := c.Serve(); err != nil {
+				failures <- fmt.Errorf("%s: %w", c.Name, err)
+			}
+		}(c)
+	}
+
+	var result error
+	select {
+	case <-ctx.Done():
+		logger.Info("Shutdown requested")
+	case result = <-failures:
+		logger.Errorf("Shutting down after a failure: %v", result)
+	}
+	if m.opts.OnShutdown != nil {
+		m.opts.OnShutdown()
+	}
+	// A failed component cannot wait for traffic to move away
+	if result == nil && m.opts.PreStopDelay > 0 {
+		logger.Infof("Serving for %v more while traffic moves away", m.opts.PreStopDelay)
+		time.Sleep(m.opts.PreStopDelay)
+	}
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), m.opts.DrainTimeout)
+	defer cancel()
+	for i := len(m.components) - 1; i >= 0; i-- {
+		c := m.components[i]
+		logger.Infof("Draining %s", c.Name)
+		if err := c.Shutdown(drainCtx); err != nil {
+			logger.Warnf("Stopping %s, which did not drain in time: %v", c.Name, err)
+			c.Stop()
+			if result == nil {
+				result = fmt.Errorf("%s: %w", c.Name, ErrDrainTimeout)
+			}
+			continue
+		}
+		logger.Infof("Stopped %s", c.Name)
+	}
+	return result
+}
+
+// GRPCServer returns a component serving server on every listener
+func GRPCServer(name string, server *grpc.Server, listeners ...net.Listener) Component {
+	return Component{
+		Name: name,
+		Serve: func() error {
+			errs := make(chan error, len(listeners))
+			for _, l := range listeners {
+				go func(l net.Listener) { errs <- server.Serve(l) }(l)
+			}
+			for range listeners {
+				if err := <-errs; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Shutdown: func(ctx context.Context) error {
+			done := make(chan struct{})
+			go func() {
+				server.GracefulStop()
+				close(done)
+			}()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+		Stop: server.Stop,
+	}
+}
+
+// HTTPServer returns a component serving server on listener, over TLS when it
+// has a TLSConfig
+func HTTPServer(name string, server *http.Server, listener net.Listener) Component {
+	return Component{
+		Name: name,
+		Serve: func() error {
+			logger.Infof("%s is listening on %s", name, listener.Addr())
+			var err error
+			if server.TLSConfig != nil {
+				// The certificates come from TLSConfig
+				err = server.ServeTLS(listener, "", "")
+			} else {
+				err = server.Serve(listener)
+			}
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return err
+		},
+		Shutdown: server.Shutdown,
+		Stop:     func() { server.Close() },
+	}
+}
+
+// Multiplexer returns a component splitting the connections of listener by
+// protocol, along with the listeners of each side. Connections opening with the
+// HTTP/2 preface, as gRPC clients do, are accepted from grpcListener and all
+// others from httpListener, so each server owns its connections and drains them
+// on shutdown. Shutting the component down closes listener; add it after both
+// servers so it stops accepting connections first.
+func Multiplexer(name string, listener net.Listener) (c Component, grpcListener, httpListener net.Listener) {
+	mux := cmux.New(listener)
+	grpcListener = sharedListener{mux.Match(cmux.HTTP2())}
+	httpListener = sharedListener{mux.Match(cmux.Any())}
+	closeListener := func() {
+		if err := listener.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			logger.Warnf("Closing the listener of %s: %v", name, err)
+		}
+	}
+	return Component{
+		Name: name,
+		Serve: func() error {
+			err := mux.Serve()
+			// The servers close the listener too, through theirs
+			if errors.Is(err, net.ErrClosed) || errors.Is(err, cmux.ErrListenerClosed) {
+				return nil
+			}
+			return err
+		},
+		Shutdown: func(context.Context) error {
+			closeListener()
+			return nil
+		},
+		Stop: closeListener,
+	}, grpcListener, httpListener
+}
+
+// sharedListener is a listener of a Multiplexer. Closing one closes the
+// listener they share, which whoever closes it last finds already closed; that
+// is not an error, or http.Server.Shutdown would report it as a failed drain.
+type sharedListener struct {
+	net.Listener
+}
+
+func (l sharedListener) Close() error {
+	if err := l.Listener.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		return err
+	}
+	return nil
+}
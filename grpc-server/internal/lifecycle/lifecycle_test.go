@@ -0,0 +1,218 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestMain(m *testing.M) {
+	// The manager logs through the global logger, which main initializes
+	logger.SetLogger(zap.NewNop().Sugar())
+	os.Exit(m.Run())
+}
+
+func TestRunShutsDownInReverseOrder(t *testing.T) {
+	var mu sync.Mutex
+	var events []string
+	record := func(event string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}
+	component := func(name string, drainErr error) Component {
+		stopped := make(chan struct{})
+		var once sync.Once
+		return Component{
+			Name:  name,
+			Serve: func() error { <-stopped; return nil },
+			Shutdown: func(ctx context.Context) error {
+				record("shutdown " + name)
+				if drainErr != nil {
+					return drainErr
+				}
+				once.Do(func() { close(stopped) })
+				return nil
+			},
+			Stop: func() {
+				record("stop " + name)
+				once.Do(func() { close(stopped) })
+			},
+		}
+	}
+
+	m := New(Options{OnShutdown: func() { record("on shutdown") }, DrainTimeout: time.Second})
+	m.Add(component("first", nil))
+	m.Add(component("second", context.DeadlineExceeded))
+	m.Add(component("third", nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := m.Run(ctx)
+	if !errors.Is(err, ErrDrainTimeout) {
+		t.Errorf("Run = %v, want ErrDrainTimeout", err)
+	}
+	want := []string{"on shutdown", "shutdown third", "shutdown second", "stop second", "shutdown first"}
+	if !slices.Equal(events, want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+}
+
+func TestRunReturnsFailure(t *testing.T) {
+	failure := errors.New("port in use")
+	m := New(Options{DrainTimeout: time.Second})
+	m.Add(Component{
+		Name:     "broken",
+		Serve:    func() error { return failure },
+		Shutdown: func(context.Context) error { return nil },
+		Stop:     func() {},
+	})
+	if err := m.Run(context.Background()); !errors.Is(err, failure) {
+		t.Errorf("Run = %v, want the failure of the component", err)
+	}
+}
+
+// multiplexed serves a gRPC health server and an HTTP handler on one port, the
+// way main does with http.multiplex_grpc
+type multiplexed struct {
+	addr   string
+	result chan error
+	stop   context.CancelFunc
+}
+
+func startMultiplexed(t *testing.T, drainTimeout time.Duration) *multiplexed {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	healthServer := health.NewServer()
+	grpcServer := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	httpServer := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	})}
+
+	m := New(Options{OnShutdown: healthServer.Shutdown, DrainTimeout: drainTimeout})
+	multiplexer, grpcListener, httpListener := Multiplexer("multiplexer", listener)
+	m.Add(GRPCServer("gRPC server", grpcServer, grpcListener))
+	m.Add(HTTPServer("HTTP server", httpServer, httpListener))
+	m.Add(multiplexer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &multiplexed{addr: listener.Addr().String(), result: make(chan error, 1), stop: cancel}
+	go func() { s.result <- m.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		grpcServer.Stop()
+	})
+	return s
+}
+
+// watch opens a health Watch stream to s and waits for its first status
+func (s *multiplexed) watch(t *testing.T, ctx context.Context) healthpb.Health_WatchClient {
+	t.Helper()
+	conn, err := grpc.NewClient(s.addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	stream, err := healthpb.NewHealthClient(conn).Watch(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	resp, err := stream.Recv()
+	if err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("first status = %v, %v; want SERVING", resp.GetStatus(), err)
+	}
+	return stream
+}
+
+// waitResult returns the result of Run, failing when it takes longer than timeout
+func (s *multiplexed) waitResult(t *testing.T, timeout time.Duration) error {
+	t.Helper()
+	select {
+	case err := <-s.result:
+		return err
+	case <-time.After(timeout):
+		t.Fatalf("Run did not return within %v", timeout)
+		return nil
+	}
+}
+
+func TestMultiplexedShutdownWaitsForStream(t *testing.T) {
+	s := startMultiplexed(t, 5*time.Second)
+	streamCtx, cancelStream := context.WithCancel(context.Background())
+	defer cancelStream()
+	stream := s.watch(t, streamCtx)
+
+	// REST requests share the port
+	resp, err := http.Get("http://" + s.addr + "/")
+	if err != nil {
+		t.Fatalf("HTTP request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "ok" {
+		t.Fatalf("HTTP response = %q", body)
+	}
+
+	s.stop()
+	// The stream stays open and learns that the server is going away
+	resp2, err := stream.Recv()
+	if err != nil || resp2.GetStatus() != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("status after shutdown = %v, %v; want NOT_SERVING", resp2.GetStatus(), err)
+	}
+	select {
+	case err := <-s.result:
+		t.Fatalf("Run returned %v while a stream was in flight", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+	if conn, err := net.DialTimeout("tcp", s.addr, time.Second); err == nil {
+		conn.Close()
+		t.Error("port still accepts connections while draining")
+	}
+
+	// Completing the stream completes the drain
+	cancelStream()
+	if err := s.waitResult(t, 5*time.Second); err != nil {
+		t.Errorf("Run = %v, want a clean shutdown", err)
+	}
+}
+
+func TestMultiplexedShutdownStopsStreamAfterDrainTimeout(t *testing.T) {
+	s := startMultiplexed(t, 300*time.Millisecond)
+	stream := s.watch(t, context.Background())
+
+	s.stop()
+	if err := s.waitResult(t, 5*time.Second); !errors.Is(err, ErrDrainTimeout) {
+		t.Errorf("Run = %v, want ErrDrainTimeout", err)
+	}
+	// The stream is closed by the server rather than left hanging
+	for {
+		_, err := stream.Recv()
+		if err == nil {
+			continue
+		}
+		if code := status.Code(err); code != codes.Unavailable {
+			t.Errorf("stream ended with %v, want Unavailable", err)
+		}
+		break
+	}
+}
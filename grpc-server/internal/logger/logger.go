@@ -1,19 +1,51 @@
 package logger
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+// Log output formats selectable in Options.Format
+const (
+	FormatJSON    = "json"    // One JSON object per line, for production
+	FormatConsole = "console" // Colored, human-readable lines, for development
+)
+
 var sugarLogger *zap.SugaredLogger
 
+// level is shared by every logger built by InitLogger so it can be changed at runtime
+var level = zap.NewAtomicLevelAt(zap.InfoLevel)
+
+// Options configures the logger
+type Options struct {
+	Format string // One of the Format* constants
+	Level  string // Minimum level: debug, info, warn or error
+}
+
 // InitLogger initializes the logger
-func InitLogger() (*zap.SugaredLogger, error) {
+func InitLogger(opts Options) (*zap.SugaredLogger, error) {
+	if err := SetLevel(opts.Level); err != nil {
+		return nil, err
+	}
+
 	// Configure Zap logger
-	config := zap.NewDevelopmentConfig()
-	config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	var config zap.Config
+	switch opts.Format {
+	case "", FormatJSON:
+		config = zap.NewProductionConfig()
+		// Sampling would drop request logs under load, as they share their message
+		config.Sampling = nil
+	case FormatConsole:
+		config = zap.NewDevelopmentConfig()
+		config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	default:
+		return nil, fmt.Errorf("unknown log format %q", opts.Format)
+	}
+	config.Level = level
 	config.EncoderConfig.TimeKey = "timestamp"
 	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 
@@ -31,11 +63,51 @@ func InitLogger() (*zap.SugaredLogger, error) {
 	return sugarLogger, nil
 }
 
+// SetLevel changes the minimum level of every logger; an empty level means info
+func SetLevel(l string) error {
+	if l == "" {
+		l = zap.InfoLevel.String()
+	}
+	parsed, err := zapcore.ParseLevel(l)
+	if err != nil {
+		return err
+	}
+	level.SetLevel(parsed)
+	return nil
+}
+
+// LevelHandler serves the current level as JSON on GET and changes it on PUT,
+// e.g. curl -X PUT -d level=debug
+func LevelHandler() http.Handler {
+	return level
+}
+
+// SetLogger replaces the logger instance, e.g. with zap.NewNop().Sugar() in tools
+func SetLogger(l *zap.SugaredLogger) {
+	sugarLogger = l
+}
+
 // GetLogger retrieves the logger instance
 func GetLogger() *zap.SugaredLogger {
 	return sugarLogger
 }
 
+type loggerKey struct{}
+
+// NewContext returns a copy of ctx whose log lines, see FromContext, go through l
+func NewContext(ctx context.Context, l *zap.SugaredLogger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger of the request ctx belongs to, carrying its
+// request ID, or the global logger outside of requests
+func FromContext(ctx context.Context) *zap.SugaredLogger {
+	if l, ok := ctx.Value(loggerKey{}).(*zap.SugaredLogger); ok {
+		return l
+	}
+	return sugarLogger
+}
+
 // Info logs an info message
 func Info(args ...interface{}) {
 	sugarLogger.Info(args...)
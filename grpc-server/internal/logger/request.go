@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIDKey is the metadata key, and HTTP header, carrying the request ID
+const RequestIDKey = "x-request-id"
+
+// maxRequestIDLength bounds the request IDs accepted from callers
+const maxRequestIDLength = 128
+
+// NewRequestID returns a random request ID
+func NewRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ValidRequestID reports whether a caller supplied request ID can be logged as is
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// requestLogger returns ctx carrying a logger tagged with the request ID from
+// the x-request-id metadata, generated when missing, and the call's method and peer
+func requestLogger(ctx context.Context, fullMethod string) (context.Context, *zap.SugaredLogger, string) {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(RequestIDKey); len(values) > 0 && ValidRequestID(values[0]) {
+			id = values[0]
+		}
+	}
+	if id == "" {
+		id = NewRequestID()
+	}
+	fields := []interface{}{"request_id", id, "grpc.method", fullMethod}
+	if p, ok := peer.FromContext(ctx); ok {
+		fields = append(fields, "peer.address", p.Addr.String())
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		fields = append(fields, "trace_id", span.TraceID().String())
+	}
+	l := sugarLogger.With(fields...)
+	return NewContext(ctx, l), l, id
+}
+
+// healthService prefixes the methods of the gRPC health service, whose
+// successful calls are only logged at debug as probes run every few seconds
+const healthService = "/grpc.health.v1.Health/"
+
+// logCompleted records the outcome of a call, at a level depending on whether
+// the caller or the server is at fault
+func logCompleted(l *zap.SugaredLogger, fullMethod string, start time.Time, err error) {
+	code := status.Code(err)
+	fields := []interface{}{"grpc.code", code.String(), "grpc.duration_ms", float64(time.Since(start).Microseconds()) / 1000}
+	switch code {
+	case codes.OK:
+		if strings.HasPrefix(fullMethod, healthService) {
+			l.Debugw("Call completed", fields...)
+			return
+		}
+		l.Infow("Call completed", fields...)
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.PermissionDenied,
+		codes.Unauthenticated, codes.ResourceExhausted, codes.FailedPrecondition, codes.OutOfRange, codes.Aborted:
+		l.Warnw("Call completed", append(fields, "error", status.Convert(err).Message())...)
+	default:
+		l.Errorw("Call completed", append(fields, "error", status.Convert(err).Message())...)
+	}
+}
+
+// UnaryServerInterceptor tags the context of unary calls with a request logger,
+// echoes the request ID in the response headers and logs each completed call
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, l, id := requestLogger(ctx, info.FullMethod)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDKey, id))
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCompleted(l, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor tags the context of streams with a request logger,
+// echoes the request ID in the response headers and logs each completed stream
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, l, id := requestLogger(ss.Context(), info.FullMethod)
+		_ = ss.SetHeader(metadata.Pairs(RequestIDKey, id))
+		start := time.Now()
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		logCompleted(l, info.FullMethod, start, err)
+		return err
+	}
+}
+
+// contextStream overrides the context of a server stream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
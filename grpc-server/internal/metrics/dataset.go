@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/database"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/logger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// countTimeout bounds how long a scrape waits for the store
+const countTimeout = 5 * time.Second
+
+var (
+	datasetUsersDesc = prometheus.NewDesc("users_dataset_users",
+		"Users currently held by the store.", nil, nil)
+	datasetReloadsDesc = prometheus.NewDesc("users_dataset_reloads_total",
+		"Successful reloads of the users file.", nil, nil)
+	datasetReloadFailuresDesc = prometheus.NewDesc("users_dataset_reload_failures_total",
+		"Failed reloads of the users file; the previous data is kept.", nil, nil)
+	datasetLastReloadDesc = prometheus.NewDesc("users_dataset_last_reload_timestamp_seconds",
+		"Unix time of the last successful reload, 0 if none.", nil, nil)
+	datasetLastReloadErrorDesc = prometheus.NewDesc("users_dataset_last_reload_error_timestamp_seconds",
+		"Unix time of the last failed reload, 0 if none.", nil, nil)
+	datasetReloadHealthyDesc = prometheus.NewDesc("users_dataset_reload_healthy",
+		"1 unless the latest reload attempt failed.", nil, nil)
+)
+
+// reloadStatuser is implemented by stores that reload their users file
+type reloadStatuser interface {
+	ReloadStatus() database.ReloadStatus
+}
+
+// datasetCollector reads the dataset gauges from the store at scrape time
+type datasetCollector struct {
+	store database.UserStore
+}
+
+// RegisterStore exports the size of store and, for the in-memory backend, the
+// outcome of its reloads
+func RegisterStore(store database.UserStore) error {
+	return prometheus.Register(&datasetCollector{store: store})
+}
+
+func (c *datasetCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- datasetUsersDesc
+	if _, ok := c.store.(reloadStatuser); ok {
+		ch <- datasetReloadsDesc
+		ch <- datasetReloadFailuresDesc
+		ch <- datasetLastReloadDesc
+		ch <- datasetLastReloadErrorDesc
+		ch <- datasetReloadHealthyDesc
+	}
+}
+
+func (c *datasetCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), countTimeout)
+	defer cancel()
+	if n, err := c.store.CountUsers(ctx); err != nil {
+		logger.Warnf("Failed to count users for metrics: %v", err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(datasetUsersDesc, prometheus.GaugeValue, float64(n))
+	}
+
+	reloader, ok := c.store.(reloadStatuser)
+	if !ok {
+		return
+	}
+	status := reloader.ReloadStatus()
+	healthy := 1.0
+	if status.LastError != "" {
+		healthy = 0
+	}
+	ch <- prometheus.MustNewConstMetric(datasetReloadsDesc, prometheus.CounterValue, float64(status.Reloads))
+	ch <- prometheus.MustNewConstMetric(datasetReloadFailuresDesc, prometheus.CounterValue, float64(status.Failures))
+	ch <- prometheus.MustNewConstMetric(datasetLastReloadDesc, prometheus.GaugeValue, unixSeconds(status.LastReload))
+	ch <- prometheus.MustNewConstMetric(datasetLastReloadErrorDesc, prometheus.GaugeValue, unixSeconds(status.LastErrorTime))
+	ch <- prometheus.MustNewConstMetric(datasetReloadHealthyDesc, prometheus.GaugeValue, healthy)
+}
+
+func unixSeconds(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.UnixNano()) / 1e9
+}
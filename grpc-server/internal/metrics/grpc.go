@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// RPC types used as the grpc_type label
+const (
+	typeUnary        = "unary"
+	typeClientStream = "client_stream"
+	typeServerStream = "server_stream"
+	typeBidiStream   = "bidi_stream"
+)
+
+// UnaryServerInterceptor records the rate, status codes and latency of unary RPCs.
+// It should come first in the chain so rejected calls are counted too.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		done := observe(typeUnary, info.FullMethod)
+		resp, err := handler(ctx, req)
+		done(err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor records the rate, status codes and duration of streaming RPCs
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		done := observe(streamType(info), info.FullMethod)
+		err := handler(srv, ss)
+		done(err)
+		return err
+	}
+}
+
+func streamType(info *grpc.StreamServerInfo) string {
+	switch {
+	case info.IsClientStream && info.IsServerStream:
+		return typeBidiStream
+	case info.IsClientStream:
+		return typeClientStream
+	default:
+		return typeServerStream
+	}
+}
+
+// observe counts a started RPC and returns the function recording its completion
+func observe(rpcType, fullMethod string) func(err error) {
+	service, method := splitMethod(fullMethod)
+	grpcStarted.WithLabelValues(rpcType, service, method).Inc()
+	start := time.Now()
+	return func(err error) {
+		code := status.Code(err)
+		grpcHandled.WithLabelValues(rpcType, service, method, code.String()).Inc()
+		grpcHandlingSeconds.WithLabelValues(rpcType, service, method).Observe(time.Since(start).Seconds())
+	}
+}
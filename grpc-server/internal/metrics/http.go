@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// InstrumentMux records the rate, status codes and latency of the requests served
+// by mux, labeled with the pattern of the matching route, see SetRoute, to keep
+// the label values bounded.
+func InstrumentMux(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		if pattern == "" {
+			pattern = "unmatched"
+		}
+		route := &pattern
+		recorder := &statusRecorder{ResponseWriter: w}
+		start := time.Now()
+		mux.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), routeKey{}, route)))
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		httpRequests.WithLabelValues(*route, r.Method, strconv.Itoa(recorder.status)).Inc()
+		httpRequestSeconds.WithLabelValues(*route, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+type routeKey struct{}
+
+// SetRoute replaces the route label of the request ctx belongs to, for handlers
+// such as the REST gateway that route requests themselves below a mux pattern
+func SetRoute(ctx context.Context, route string) {
+	if r, ok := ctx.Value(routeKey{}).(*string); ok {
+		*r = route
+	}
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+// Flush keeps streaming responses working through the recorder
+func (s *statusRecorder) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap gives http.ResponseController access to the underlying writer
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Path is where the gateway serves the metrics
+const Path = "/metrics"
+
+// Latency buckets in seconds, from sub-millisecond lookups to full scans of large datasets
+var latencyBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// gRPC server metrics, following the names of go-grpc-prometheus
+var (
+	grpcStarted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_started_total",
+		Help: "RPCs started on the server.",
+	}, []string{"grpc_type", "grpc_service", "grpc_method"})
+	grpcHandled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_handled_total",
+		Help: "RPCs completed on the server, by status code.",
+	}, []string{"grpc_type", "grpc_service", "grpc_method", "grpc_code"})
+	grpcHandlingSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_handling_seconds",
+		Help:    "Time until RPCs were completed by the server.",
+		Buckets: latencyBuckets,
+	}, []string{"grpc_type", "grpc_service", "grpc_method"})
+)
+
+// HTTP gateway metrics
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests completed by the gateway, by route, method and status code.",
+	}, []string{"route", "method", "code"})
+	httpRequestSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time until HTTP requests were completed by the gateway.",
+		Buckets: latencyBuckets,
+	}, []string{"route", "method"})
+)
+
+// Handler serves every registered metric in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// splitMethod splits a full gRPC method name, e.g. "/users.UserService/GetUserByID"
+func splitMethod(fullMethod string) (service, method string) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return "unknown", "unknown"
+	}
+	return service, method
+}
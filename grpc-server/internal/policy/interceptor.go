@@ -0,0 +1,139 @@
+package policy
+
+import (
+	"context"
+	"strings"
+
+	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/auth"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// governs reports whether the policy applies to a method; RPCs of other
+// services, e.g. health checks, are left to the authentication interceptors
+func governs(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/"+pb.UserService_ServiceDesc.ServiceName+"/")
+}
+
+// authorize returns the grant of the caller of fullMethod, failing when none of its
+// roles may call it or when req filters or sorts on a field the caller can't see
+func (p *Policy) authorize(ctx context.Context, fullMethod string, req interface{}) (*grant, error) {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+	g := p.grantFor(principal.Roles)
+	if g == nil || !g.methods[fullMethod] {
+		logger.FromContext(ctx).Warnf("Denied %s to %s with roles %v", fullMethod, principal.Subject, principal.Roles)
+		return nil, status.Errorf(codes.PermissionDenied, "not allowed to call %s", fullMethod)
+	}
+	// Filtering or sorting on a redacted field would reveal its values
+	for _, field := range queriedFields(req) {
+		if v, ok := g.fields[field]; ok && v != shown {
+			return nil, status.Errorf(codes.PermissionDenied, "not allowed to filter or sort on field %q", field)
+		}
+	}
+	return g, nil
+}
+
+// queriedFields lists the fields a request filters or sorts on
+func queriedFields(req interface{}) []string {
+	var fields []string
+	switch r := req.(type) {
+	case *pb.GetUsersByIDRequest:
+		fields = orderByFields(r.GetOrderBy())
+	case *pb.SearchUsersRequest:
+		fields = orderByFields(r.GetOrderBy())
+		for _, criteria := range r.GetCriterias() {
+			fields = append(fields, criteria.GetFieldName())
+		}
+		fields = appendFilterFields(fields, r.GetFilter())
+	}
+	return fields
+}
+
+func appendFilterFields(fields []string, f *pb.SearchFilter) []string {
+	if f == nil {
+		return fields
+	}
+	if criteria := f.GetCriteria(); criteria != nil {
+		return append(fields, criteria.GetFieldName())
+	}
+	for _, child := range f.GetGroup().GetFilters() {
+		fields = appendFilterFields(fields, child)
+	}
+	return fields
+}
+
+// orderByFields returns the field names of an order_by clause, which is validated by the store
+func orderByFields(orderBy string) []string {
+	var fields []string
+	for _, part := range strings.Split(orderBy, ",") {
+		if tokens := strings.Fields(part); len(tokens) > 0 {
+			fields = append(fields, tokens[0])
+		}
+	}
+	return fields
+}
+
+// UnaryServerInterceptor rejects calls the caller's roles don't allow and redacts the users in responses
+func (p *Policy) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !governs(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		g, err := p.authorize(ctx, info.FullMethod, req)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return g.redactResponse(resp), nil
+	}
+}
+
+// StreamServerInterceptor rejects streams the caller's roles don't allow and redacts the users they send
+func (p *Policy) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !governs(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		// The request is only known once the handler receives it, so the
+		// stream is authorized on its first RecvMsg
+		return handler(srv, &redactingStream{ServerStream: ss, policy: p, method: info.FullMethod})
+	}
+}
+
+// redactingStream authorizes the request of a stream and redacts the users sent on it
+type redactingStream struct {
+	grpc.ServerStream
+	policy *Policy
+	method string
+	grant  *grant
+}
+
+func (s *redactingStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if s.grant == nil {
+		g, err := s.policy.authorize(s.Context(), s.method, m)
+		if err != nil {
+			return err
+		}
+		s.grant = g
+	}
+	return nil
+}
+
+func (s *redactingStream) SendMsg(m interface{}) error {
+	if s.grant == nil {
+		return status.Error(codes.PermissionDenied, "request was not authorized")
+	}
+	return s.ServerStream.SendMsg(s.grant.redactResponse(m))
+}
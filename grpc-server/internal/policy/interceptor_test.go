@@ -0,0 +1,142 @@
+package policy
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const viewerPolicy = `
+roles:
+  viewer:
+    methods: [GetUserByID, GetUsersByID, SearchUsers, StreamSearchUsers]
+    fields: {phone: mask, married: hide}
+  admin:
+    methods: ["*"]
+`
+
+func criteriaOn(field string) *pb.SearchCriteria {
+	return &pb.SearchCriteria{FieldName: field, FieldValue: "1"}
+}
+
+func leafOn(field string) *pb.SearchFilter {
+	return &pb.SearchFilter{Filter: &pb.SearchFilter_Criteria{Criteria: criteriaOn(field)}}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := loadPolicy(t, viewerPolicy).UnaryServerInterceptor()
+	viewer := auth.NewContext(context.Background(), &auth.Principal{Subject: "ann", Roles: []string{"viewer"}})
+	stored := &pb.User{Id: 1, Fname: "Ann", City: "Oslo", Phone: 5551234567, Height: 170, Married: true}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		method string
+		req    interface{}
+		code   codes.Code
+	}{
+		{"visible criteria", viewer, pb.UserService_SearchUsers_FullMethodName,
+			&pb.SearchUsersRequest{Criterias: []*pb.SearchCriteria{criteriaOn("city")}, OrderBy: "height desc"}, codes.OK},
+		{"masked criteria", viewer, pb.UserService_SearchUsers_FullMethodName,
+			&pb.SearchUsersRequest{Criterias: []*pb.SearchCriteria{criteriaOn("phone")}}, codes.PermissionDenied},
+		{"hidden field in a nested filter", viewer, pb.UserService_SearchUsers_FullMethodName,
+			&pb.SearchUsersRequest{Filter: &pb.SearchFilter{Filter: &pb.SearchFilter_Group{Group: &pb.FilterGroup{
+				Logic:   pb.FilterGroup_NOT,
+				Filters: []*pb.SearchFilter{leafOn("city"), leafOn("married")},
+			}}}}, codes.PermissionDenied},
+		{"masked sort key", viewer, pb.UserService_SearchUsers_FullMethodName,
+			&pb.SearchUsersRequest{OrderBy: "height, phone desc"}, codes.PermissionDenied},
+		{"hidden sort key by ID", viewer, pb.UserService_GetUsersByID_FullMethodName,
+			&pb.GetUsersByIDRequest{UserIds: []int32{1}, OrderBy: "married"}, codes.PermissionDenied},
+		{"admin may query every field", auth.NewContext(context.Background(), &auth.Principal{Subject: "root", Roles: []string{"admin"}}),
+			pb.UserService_SearchUsers_FullMethodName, &pb.SearchUsersRequest{Criterias: []*pb.SearchCriteria{criteriaOn("phone")}, OrderBy: "married"}, codes.OK},
+		{"method not granted", viewer, pb.UserService_DeleteUser_FullMethodName, &pb.DeleteUserRequest{UserId: 1}, codes.PermissionDenied},
+		{"no known role", auth.NewContext(context.Background(), &auth.Principal{Subject: "bob", Roles: []string{"guest"}}),
+			pb.UserService_GetUserByID_FullMethodName, &pb.GetUserByIDRequest{UserId: 1}, codes.PermissionDenied},
+		{"unauthenticated", context.Background(), pb.UserService_GetUserByID_FullMethodName, &pb.GetUserByIDRequest{UserId: 1}, codes.Unauthenticated},
+		{"other service", context.Background(), "/grpc.health.v1.Health/Check", nil, codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := func(context.Context, interface{}) (interface{}, error) {
+				called = true
+				return stored, nil
+			}
+			resp, err := interceptor(tt.ctx, tt.req, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if code := status.Code(err); code != tt.code {
+				t.Fatalf("code = %v, want %v (%v)", code, tt.code, err)
+			}
+			if tt.code != codes.OK {
+				if called {
+					t.Error("handler called for a rejected request")
+				}
+				return
+			}
+			if !called {
+				t.Fatal("handler not called")
+			}
+			if p, _ := auth.FromContext(tt.ctx); p != nil && p.HasRole("viewer") {
+				want := &pb.User{Id: 1, Fname: "Ann", City: "Oslo", Phone: 4567, Height: 170}
+				if !proto.Equal(resp.(*pb.User), want) {
+					t.Errorf("response = %v, want %v", resp, want)
+				}
+			}
+		})
+	}
+}
+
+// fakeStream receives req and records the messages sent
+type fakeStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	req  proto.Message
+	sent []interface{}
+}
+
+func (s *fakeStream) Context() context.Context { return s.ctx }
+
+func (s *fakeStream) RecvMsg(m interface{}) error {
+	proto.Merge(m.(proto.Message), s.req)
+	return nil
+}
+
+func (s *fakeStream) SendMsg(m interface{}) error {
+	s.sent = append(s.sent, m)
+	return nil
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	interceptor := loadPolicy(t, viewerPolicy).StreamServerInterceptor()
+	viewer := auth.NewContext(context.Background(), &auth.Principal{Subject: "ann", Roles: []string{"viewer"}})
+	info := &grpc.StreamServerInfo{FullMethod: pb.UserService_StreamSearchUsers_FullMethodName, IsServerStream: true}
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		req := &pb.SearchUsersRequest{}
+		if err := ss.RecvMsg(req); err != nil {
+			return err
+		}
+		return ss.SendMsg(&pb.User{Id: 1, Phone: 5551234567, Married: true})
+	}
+
+	stream := &fakeStream{ctx: viewer, req: &pb.SearchUsersRequest{Criterias: []*pb.SearchCriteria{criteriaOn("city")}}}
+	if err := interceptor(nil, stream, info, handler); err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	if len(stream.sent) != 1 || !proto.Equal(stream.sent[0].(*pb.User), &pb.User{Id: 1, Phone: 4567}) {
+		t.Errorf("sent %v, want the redacted user", stream.sent)
+	}
+
+	stream = &fakeStream{ctx: viewer, req: &pb.SearchUsersRequest{Filter: leafOn("phone")}}
+	if err := interceptor(nil, stream, info, handler); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("stream filtering on a masked field: %v, want PermissionDenied", err)
+	}
+	if len(stream.sent) != 0 {
+		t.Errorf("rejected stream sent %v", stream.sent)
+	}
+}
@@ -0,0 +1,30 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/logger"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	// Denied calls are logged through the global logger, which main initializes
+	logger.SetLogger(zap.NewNop().Sugar())
+	os.Exit(m.Run())
+}
+
+// loadPolicy loads a policy file holding data
+func loadPolicy(t *testing.T, data string) *Policy {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return p
+}
@@ -0,0 +1,178 @@
+package policy
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+
+	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// Visibilities of a User field, as written in the policy file
+const (
+	Show = "show" // Returned as stored
+	Mask = "mask" // Partially returned, see maskUser
+	Hide = "hide" // Cleared from responses
+)
+
+// allMethods grants every RPC of the UserService
+const allMethods = "*"
+
+// visibility orders the field visibilities from most to least restrictive
+type visibility int
+
+const (
+	hidden visibility = iota
+	masked
+	shown
+)
+
+// redactedFields are the User fields a policy controls; id is always returned
+var redactedFields = []string{utils.FIRSTNAME, utils.CITY, utils.PHONE, utils.HEIGHT, utils.MARRIED}
+
+// maskable reports whether a field has a partial form, married is either shown or hidden
+func maskable(field string) bool {
+	return field != utils.MARRIED
+}
+
+// roleEntry is one role of the policy file
+type roleEntry struct {
+	Methods []string          `yaml:"methods"` // UserService RPCs the role may call, "*" for all
+	Fields  map[string]string `yaml:"fields"`  // Visibility of each User field, show when unset
+}
+
+// grant is what a caller may do, merged from all of its roles
+type grant struct {
+	methods map[string]bool       // Full method names
+	fields  map[string]visibility // Every field of redactedFields
+}
+
+// Policy maps roles to the RPCs they may call and the User fields they may see
+type Policy struct {
+	roles map[string]*grant
+}
+
+// Load reads a YAML policy file of the form
+//
+//	roles:
+//	  viewer:
+//	    methods: [GetUserByID, GetUsersByID, SearchUsers]
+//	    fields: {phone: mask, height: hide, married: hide}
+//	  admin:
+//	    methods: ["*"]
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading policy file: %v", err)
+	}
+	var file struct {
+		Roles map[string]roleEntry `yaml:"roles"`
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("error parsing policy file %s: %v", path, err)
+	}
+	if len(file.Roles) == 0 {
+		return nil, fmt.Errorf("policy file %s defines no roles", path)
+	}
+
+	p := &Policy{roles: make(map[string]*grant, len(file.Roles))}
+	for name, entry := range file.Roles {
+		g, err := newGrant(entry)
+		if err != nil {
+			return nil, fmt.Errorf("policy file %s: role %q: %v", path, name, err)
+		}
+		p.roles[name] = g
+	}
+	return p, nil
+}
+
+func newGrant(entry roleEntry) (*grant, error) {
+	known := make(map[string]string)
+	for _, desc := range pb.UserService_ServiceDesc.Methods {
+		known[desc.MethodName] = fullMethod(desc.MethodName)
+	}
+	for _, desc := range pb.UserService_ServiceDesc.Streams {
+		known[desc.StreamName] = fullMethod(desc.StreamName)
+	}
+
+	g := &grant{methods: make(map[string]bool), fields: make(map[string]visibility, len(redactedFields))}
+	for _, method := range entry.Methods {
+		if method == allMethods {
+			for _, full := range known {
+				g.methods[full] = true
+			}
+			continue
+		}
+		full, ok := known[method]
+		if !ok {
+			return nil, fmt.Errorf("unknown method %q", method)
+		}
+		g.methods[full] = true
+	}
+
+	for _, field := range redactedFields {
+		g.fields[field] = shown
+	}
+	for field, value := range entry.Fields {
+		if _, ok := g.fields[field]; !ok {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+		switch value {
+		case Show:
+		case Mask:
+			if !maskable(field) {
+				return nil, fmt.Errorf("field %q cannot be masked, use %s or %s", field, Show, Hide)
+			}
+			g.fields[field] = masked
+		case Hide:
+			g.fields[field] = hidden
+		default:
+			return nil, fmt.Errorf("field %q: visibility must be %s, %s or %s, got %q", field, Show, Mask, Hide, value)
+		}
+	}
+	return g, nil
+}
+
+func fullMethod(name string) string {
+	return "/" + pb.UserService_ServiceDesc.ServiceName + "/" + name
+}
+
+// grantFor merges the grants of roles, taking the most permissive of each
+// permission. It returns nil when none of the roles is in the policy.
+func (p *Policy) grantFor(roles []string) *grant {
+	var merged *grant
+	for _, role := range roles {
+		g, ok := p.roles[role]
+		if !ok {
+			continue
+		}
+		if merged == nil {
+			merged = &grant{methods: make(map[string]bool), fields: make(map[string]visibility, len(g.fields))}
+			for field := range g.fields {
+				merged.fields[field] = hidden
+			}
+		}
+		for method := range g.methods {
+			merged.methods[method] = true
+		}
+		for field, v := range g.fields {
+			merged.fields[field] = max(merged.fields[field], v)
+		}
+	}
+	return merged
+}
+
+// Roles returns the names of the roles in the policy, sorted
+func (p *Policy) Roles() []string {
+	names := make([]string, 0, len(p.roles))
+	for name := range p.roles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,91 @@
+package policy
+
+import (
+	"math"
+	"unicode/utf8"
+
+	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/utils"
+	"google.golang.org/protobuf/proto"
+)
+
+// phoneMaskModulus keeps the last 4 digits of a masked phone number
+const phoneMaskModulus = 10000
+
+// heightMaskStep is the granularity masked heights are rounded to
+const heightMaskStep = 10
+
+// showsAll reports whether the grant shows every field, so responses can be returned untouched
+func (g *grant) showsAll() bool {
+	for _, v := range g.fields {
+		if v != shown {
+			return false
+		}
+	}
+	return true
+}
+
+// redactResponse applies the field visibilities to the users of a response
+func (g *grant) redactResponse(resp interface{}) interface{} {
+	if g.showsAll() {
+		return resp
+	}
+	switch r := resp.(type) {
+	case *pb.User:
+		return g.redactUser(r)
+	case *pb.UsersList:
+		list := &pb.UsersList{NextPageToken: r.NextPageToken, TotalSize: r.TotalSize, Users: make([]*pb.User, len(r.Users))}
+		for i, user := range r.Users {
+			list.Users[i] = g.redactUser(user)
+		}
+		return list
+	default:
+		return resp
+	}
+}
+
+// redactUser returns a copy of user with the fields the grant doesn't show masked or
+// cleared. The store may hand out its own instances, so user is never modified.
+func (g *grant) redactUser(user *pb.User) *pb.User {
+	if user == nil {
+		return nil
+	}
+	u := proto.Clone(user).(*pb.User)
+	switch g.fields[utils.FIRSTNAME] {
+	case masked:
+		u.Fname = maskString(u.Fname)
+	case hidden:
+		u.Fname = ""
+	}
+	switch g.fields[utils.CITY] {
+	case masked:
+		u.City = maskString(u.City)
+	case hidden:
+		u.City = ""
+	}
+	switch g.fields[utils.PHONE] {
+	case masked:
+		u.Phone %= phoneMaskModulus
+	case hidden:
+		u.Phone = 0
+	}
+	switch g.fields[utils.HEIGHT] {
+	case masked:
+		u.Height = float32(math.Round(float64(u.Height)/heightMaskStep) * heightMaskStep)
+	case hidden:
+		u.Height = 0
+	}
+	if g.fields[utils.MARRIED] == hidden {
+		u.Married = false
+	}
+	return u
+}
+
+// maskString keeps the first character of s
+func maskString(s string) string {
+	if s == "" {
+		return ""
+	}
+	_, size := utf8.DecodeRuneInString(s)
+	return s[:size] + "***"
+}
@@ -0,0 +1,106 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const redactPolicy = `
+roles:
+  shower:
+    methods: ["*"]
+  masker:
+    methods: ["*"]
+    fields: {fname: mask, city: mask, phone: mask, height: mask, married: show}
+  hider:
+    methods: ["*"]
+    fields: {fname: hide, city: hide, phone: hide, height: hide, married: hide}
+`
+
+func redactUser() *pb.User {
+	return &pb.User{Id: 7, Fname: "Émile", City: "Oslo", Phone: 5551234567, Height: 176.4, Married: true}
+}
+
+func TestRedactResponse(t *testing.T) {
+	p := loadPolicy(t, redactPolicy)
+	tests := []struct {
+		name  string
+		roles []string
+		want  *pb.User
+	}{
+		{"show", []string{"shower"}, redactUser()},
+		{"mask", []string{"masker"}, &pb.User{Id: 7, Fname: "É***", City: "O***", Phone: 4567, Height: 180, Married: true}},
+		{"hide", []string{"hider"}, &pb.User{Id: 7}},
+		// The most permissive visibility of the roles wins
+		{"mask and hide", []string{"hider", "masker"}, &pb.User{Id: 7, Fname: "É***", City: "O***", Phone: 4567, Height: 180, Married: true}},
+		{"hide and show", []string{"hider", "shower"}, redactUser()},
+		{"unknown role ignored", []string{"hider", "nobody"}, &pb.User{Id: 7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := p.grantFor(tt.roles)
+			user := redactUser()
+			got, ok := g.redactResponse(user).(*pb.User)
+			if !ok {
+				t.Fatalf("redactResponse returned %T", g.redactResponse(user))
+			}
+			if !proto.Equal(got, tt.want) {
+				t.Errorf("redacted user = %v, want %v", got, tt.want)
+			}
+			if !proto.Equal(user, redactUser()) {
+				t.Errorf("the user of the store was modified: %v", user)
+			}
+
+			list := &pb.UsersList{Users: []*pb.User{redactUser(), nil}, NextPageToken: "next", TotalSize: 9}
+			gotList := g.redactResponse(list).(*pb.UsersList)
+			if !proto.Equal(gotList.Users[0], tt.want) || gotList.Users[1] != nil {
+				t.Errorf("redacted list = %v", gotList.Users)
+			}
+			if gotList.NextPageToken != "next" || gotList.TotalSize != 9 {
+				t.Errorf("list paging lost: %q, %d", gotList.NextPageToken, gotList.TotalSize)
+			}
+		})
+	}
+}
+
+func TestRedactLeavesOtherResponses(t *testing.T) {
+	g := loadPolicy(t, redactPolicy).grantFor([]string{"hider"})
+	resp := &emptypb.Empty{}
+	if got := g.redactResponse(resp); got != resp {
+		t.Errorf("redactResponse replaced a %T", resp)
+	}
+}
+
+func TestMaskString(t *testing.T) {
+	for in, want := range map[string]string{"": "", "a": "a***", "Oslo": "O***", "Łódź": "Ł***"} {
+		if got := maskString(in); got != want {
+			t.Errorf("maskString(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestLoadRejectsInvalidVisibility(t *testing.T) {
+	tests := map[string]string{
+		"masked married": "roles:\n  r:\n    fields: {married: mask}\n",
+		"unknown level":  "roles:\n  r:\n    fields: {phone: blur}\n",
+		"unknown field":  "roles:\n  r:\n    fields: {password: hide}\n",
+		"unknown method": "roles:\n  r:\n    methods: [DropUsers]\n",
+		"no roles":       "roles: {}\n",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policy.yaml")
+			if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Load(path); err == nil {
+				t.Error("policy accepted")
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/logger"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// UnaryServerInterceptor rejects unary calls over the caller's limit with ResourceExhausted
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if delay := l.Wait(ctx, info.FullMethod); delay > 0 {
+			_ = grpc.SetTrailer(ctx, retryAfter(delay))
+			return nil, exhausted(info.FullMethod, delay)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor rejects streams over the caller's limit with ResourceExhausted
+func (l *Limiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if delay := l.Wait(ss.Context(), info.FullMethod); delay > 0 {
+			ss.SetTrailer(retryAfter(delay))
+			return exhausted(info.FullMethod, delay)
+		}
+		return handler(srv, ss)
+	}
+}
+
+// RetryAfterSeconds rounds delay up to whole seconds, as used by the retry-after
+// trailer and the Retry-After HTTP header
+func RetryAfterSeconds(delay time.Duration) int {
+	return max(1, int(math.Ceil(delay.Seconds())))
+}
+
+func retryAfter(delay time.Duration) metadata.MD {
+	return metadata.Pairs(RetryAfterKey, strconv.Itoa(RetryAfterSeconds(delay)))
+}
+
+// exhausted builds the status of a rejected call, carrying the delay as RetryInfo
+func exhausted(fullMethod string, delay time.Duration) error {
+	logger.Debugf("Rate limit exceeded for %s, retry after %v", fullMethod, delay)
+	st := status.Newf(codes.ResourceExhausted, "rate limit exceeded for %s, retry after %ds", fullMethod, RetryAfterSeconds(delay))
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(delay)})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// transportStream records the trailer a unary handler sets
+type transportStream struct {
+	grpc.ServerTransportStream
+	trailer metadata.MD
+}
+
+func (s *transportStream) Method() string { return pb.UserService_SearchUsers_FullMethodName }
+
+func (s *transportStream) SetTrailer(md metadata.MD) error {
+	s.trailer = metadata.Join(s.trailer, md)
+	return nil
+}
+
+// serverStream records the trailer of a stream
+type serverStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	trailer metadata.MD
+}
+
+func (s *serverStream) Context() context.Context  { return s.ctx }
+func (s *serverStream) SetTrailer(md metadata.MD) { s.trailer = metadata.Join(s.trailer, md) }
+
+// assertExhausted checks err is a ResourceExhausted status carrying a RetryInfo of
+// at most max, and trailer the matching retry-after in whole seconds
+func assertExhausted(t *testing.T, err error, trailer metadata.MD, max time.Duration) {
+	t.Helper()
+	st, _ := status.FromError(err)
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("code = %v, want ResourceExhausted (%v)", st.Code(), err)
+	}
+	var info *errdetails.RetryInfo
+	for _, detail := range st.Details() {
+		if d, ok := detail.(*errdetails.RetryInfo); ok {
+			info = d
+		}
+	}
+	if info == nil {
+		t.Fatalf("status %v has no RetryInfo", st.Proto())
+	}
+	delay := info.GetRetryDelay().AsDuration()
+	if delay <= 0 || delay > max {
+		t.Errorf("retry delay = %v, want at most %v", delay, max)
+	}
+	if got := trailer.Get(RetryAfterKey); len(got) != 1 || got[0] != "2" {
+		t.Errorf("retry-after trailer = %v, want the delay rounded up to 2 seconds", got)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	l, err := New(Options{Default: Limit{RPS: 0.5, Burst: 1}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	interceptor := l.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: pb.UserService_SearchUsers_FullMethodName}
+	calls := 0
+	handler := func(context.Context, interface{}) (interface{}, error) {
+		calls++
+		return &pb.UsersList{}, nil
+	}
+
+	stream := &transportStream{}
+	ctx := grpc.NewContextWithServerTransportStream(fromAddr(tcpAddr("192.0.2.1")), stream)
+	if _, err := interceptor(ctx, &pb.SearchUsersRequest{}, info, handler); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	_, err = interceptor(ctx, &pb.SearchUsersRequest{}, info, handler)
+	assertExhausted(t, err, stream.trailer, 2*time.Second)
+	if calls != 1 {
+		t.Errorf("handler called %d times, want only for the allowed call", calls)
+	}
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	l, err := New(Options{Methods: map[string]Limit{"StreamSearchUsers": {RPS: 0.5, Burst: 1}}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	interceptor := l.StreamServerInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: pb.UserService_StreamSearchUsers_FullMethodName, IsServerStream: true}
+	calls := 0
+	handler := func(interface{}, grpc.ServerStream) error {
+		calls++
+		return nil
+	}
+
+	stream := &serverStream{ctx: fromAddr(tcpAddr("192.0.2.1"))}
+	if err := interceptor(nil, stream, info, handler); err != nil {
+		t.Fatalf("first stream: %v", err)
+	}
+	err = interceptor(nil, stream, info, handler)
+	assertExhausted(t, err, stream.trailer, 2*time.Second)
+	if calls != 1 {
+		t.Errorf("handler called %d times, want only for the allowed stream", calls)
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	for delay, want := range map[time.Duration]int{
+		time.Millisecond:        1,
+		time.Second:             1,
+		time.Second + 1:         2,
+		90 * time.Second:        90,
+		1500 * time.Millisecond: 2,
+	} {
+		if got := RetryAfterSeconds(delay); got != want {
+			t.Errorf("RetryAfterSeconds(%v) = %d, want %d", delay, got, want)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/auth"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// Metadata keys used by the limiter
+const (
+	RetryAfterKey   = "retry-after"     // Trailer holding the seconds to wait before retrying
+	ForwardedForKey = "x-forwarded-for" // Client IP set by a trusted proxy such as the HTTP gateway
+)
+
+// sweepInterval is how often idle buckets are dropped
+const sweepInterval = time.Minute
+
+// Limit is a token bucket refilled with RPS tokens per second, holding at most Burst tokens
+type Limit struct {
+	RPS   float64 `yaml:"rps" toml:"rps"`     // Sustained requests per second, 0 disables the limit
+	Burst int     `yaml:"burst" toml:"burst"` // Requests allowed at once, at least 1; the RPS rounded up when 0
+}
+
+// Enabled reports whether the limit restricts anything
+func (l Limit) Enabled() bool {
+	return l.RPS > 0
+}
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return max(1, int(math.Ceil(l.RPS)))
+}
+
+// Options configures a Limiter
+type Options struct {
+	Default        Limit            // Limit of every UserService method not in Methods
+	Methods        map[string]Limit // Limits by method name, e.g. "SearchUsers"
+	TrustedProxies []string         // IPs or CIDRs whose x-forwarded-for metadata names the client
+}
+
+// Limiter rate limits the UserService methods per client. Clients are told
+// apart by their authenticated principal, or by their IP when anonymous.
+type Limiter struct {
+	def     Limit
+	methods map[string]Limit // By full method name
+	proxies []netip.Prefix
+
+	mu        sync.Mutex
+	buckets   map[bucketKey]*rate.Limiter
+	lastSweep time.Time
+}
+
+type bucketKey struct {
+	method string
+	client string
+}
+
+// New returns a limiter for opts
+func New(opts Options) (*Limiter, error) {
+	l := &Limiter{
+		def:     opts.Default,
+		methods: make(map[string]Limit, len(opts.Methods)),
+		buckets: make(map[bucketKey]*rate.Limiter),
+	}
+	known := make(map[string]bool)
+	for _, desc := range pb.UserService_ServiceDesc.Methods {
+		known[desc.MethodName] = true
+	}
+	for _, desc := range pb.UserService_ServiceDesc.Streams {
+		known[desc.StreamName] = true
+	}
+	for name, limit := range opts.Methods {
+		if !known[name] {
+			return nil, fmt.Errorf("rate limit for unknown method %q", name)
+		}
+		if limit.RPS < 0 || limit.Burst < 0 {
+			return nil, fmt.Errorf("rate limit of %s must not be negative", name)
+		}
+		l.methods[fullMethod(name)] = limit
+	}
+	for _, proxy := range opts.TrustedProxies {
+		prefix, err := parsePrefix(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %v", proxy, err)
+		}
+		l.proxies = append(l.proxies, prefix)
+	}
+	return l, nil
+}
+
+func fullMethod(name string) string {
+	return "/" + pb.UserService_ServiceDesc.ServiceName + "/" + name
+}
+
+// parsePrefix accepts a CIDR or a single IP
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		return netip.ParsePrefix(s)
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// limitFor returns the limit of fullMethod; methods of other services, e.g.
+// health checks, are never limited
+func (l *Limiter) limitFor(fullMethod string) (Limit, bool) {
+	if !strings.HasPrefix(fullMethod, "/"+pb.UserService_ServiceDesc.ServiceName+"/") {
+		return Limit{}, false
+	}
+	limit, ok := l.methods[fullMethod]
+	if !ok {
+		limit = l.def
+	}
+	return limit, limit.Enabled()
+}
+
+// Wait takes a token for a call of fullMethod by the client of ctx. It returns
+// zero when the call may proceed, or how long the client must wait otherwise.
+func (l *Limiter) Wait(ctx context.Context, fullMethod string) time.Duration {
+	limit, ok := l.limitFor(fullMethod)
+	if !ok {
+		return 0
+	}
+	key := bucketKey{method: fullMethod, client: l.clientKey(ctx)}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.sweep(now)
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = rate.NewLimiter(rate.Limit(limit.RPS), limit.burst())
+		l.buckets[key] = bucket
+	}
+	reservation := bucket.ReserveN(now, 1)
+	delay := reservation.DelayFrom(now)
+	if delay > 0 {
+		// The call is rejected rather than delayed, so give the token back
+		reservation.CancelAt(now)
+	}
+	return delay
+}
+
+// sweep drops the buckets that have refilled completely, which behave exactly
+// like new ones. It runs at most once per sweepInterval; l.mu must be held.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, bucket := range l.buckets {
+		if bucket.TokensAt(now) >= float64(bucket.Burst()) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// clientKey identifies the caller of ctx
+func (l *Limiter) clientKey(ctx context.Context) string {
+	if principal, ok := auth.FromContext(ctx); ok {
+		return "principal:" + principal.Subject
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "unknown"
+	}
+	// Calls over the in-memory listener come from the gateway of this process
+	if p.Addr.Network() == inProcessNetwork {
+		if forwarded := forwardedFor(ctx); forwarded != "" {
+			return "ip:" + forwarded
+		}
+		return "addr:" + p.Addr.String()
+	}
+	host := p.Addr.String()
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return "addr:" + host
+	}
+	addr = addr.Unmap()
+	if l.trusted(addr) {
+		if forwarded := forwardedFor(ctx); forwarded != "" {
+			return "ip:" + forwarded
+		}
+	}
+	return "ip:" + addr.String()
+}
+
+// inProcessNetwork is the network of the addresses of bufconn listeners
+const inProcessNetwork = "bufconn"
+
+// forwardedFor returns the client IP of ctx set by a proxy, or ""
+func forwardedFor(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(ForwardedForKey)
+	if len(values) == 0 {
+		return ""
+	}
+	// The last hop was added by the trusted proxy itself
+	hops := strings.Split(values[len(values)-1], ",")
+	return strings.TrimSpace(hops[len(hops)-1])
+}
+
+func (l *Limiter) trusted(addr netip.Addr) bool {
+	for _, prefix := range l.proxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
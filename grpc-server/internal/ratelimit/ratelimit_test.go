@@ -0,0 +1,157 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/auth"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// fromAddr returns a context of a call from addr, with the given x-forwarded-for values
+func fromAddr(addr net.Addr, forwarded ...string) context.Context {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+	if len(forwarded) > 0 {
+		md := metadata.MD{}
+		md.Append(ForwardedForKey, forwarded...)
+		ctx = metadata.NewIncomingContext(ctx, md)
+	}
+	return ctx
+}
+
+func tcpAddr(ip string) net.Addr {
+	return &net.TCPAddr{IP: net.ParseIP(ip), Port: 40000}
+}
+
+// bufconnAddr is the address of a call over the in-process listener
+type bufconnAddr struct{}
+
+func (bufconnAddr) Network() string { return inProcessNetwork }
+func (bufconnAddr) String() string  { return "bufconn" }
+
+func TestClientKey(t *testing.T) {
+	l, err := New(Options{TrustedProxies: []string{"10.0.0.0/8", "::1"}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	withPrincipal := func(ctx context.Context) context.Context {
+		return auth.NewContext(ctx, &auth.Principal{Subject: "alice"})
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{"principal over its IP", withPrincipal(fromAddr(tcpAddr("192.0.2.1"))), "principal:alice"},
+		{"principal over a forwarded IP", withPrincipal(fromAddr(bufconnAddr{}, "198.51.100.7")), "principal:alice"},
+		{"anonymous IP", fromAddr(tcpAddr("192.0.2.1")), "ip:192.0.2.1"},
+		{"IPv4-mapped IP", fromAddr(tcpAddr("::ffff:192.0.2.1")), "ip:192.0.2.1"},
+		{"forwarded by an untrusted peer", fromAddr(tcpAddr("192.0.2.1"), "198.51.100.7"), "ip:192.0.2.1"},
+		{"forwarded by a trusted CIDR", fromAddr(tcpAddr("10.1.2.3"), "198.51.100.7"), "ip:198.51.100.7"},
+		{"forwarded by a trusted IP", fromAddr(tcpAddr("::1"), "198.51.100.7"), "ip:198.51.100.7"},
+		{"last hop of a chain", fromAddr(tcpAddr("10.1.2.3"), "203.0.113.9, 198.51.100.7"), "ip:198.51.100.7"},
+		{"last header added by the proxy", fromAddr(tcpAddr("10.1.2.3"), "203.0.113.9", "198.51.100.7"), "ip:198.51.100.7"},
+		{"trusted peer without forwarded IP", fromAddr(tcpAddr("10.1.2.3")), "ip:10.1.2.3"},
+		{"in-process gateway", fromAddr(bufconnAddr{}, "198.51.100.7"), "ip:198.51.100.7"},
+		{"in-process call without forwarded IP", fromAddr(bufconnAddr{}), "addr:bufconn"},
+		{"no peer", context.Background(), "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := l.clientKey(tt.ctx); got != tt.want {
+				t.Errorf("clientKey = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientKeyTrustsNoProxyByDefault(t *testing.T) {
+	l, err := New(Options{})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if got := l.clientKey(fromAddr(tcpAddr("127.0.0.1"), "198.51.100.7")); got != "ip:127.0.0.1" {
+		t.Errorf("clientKey = %q, want the loopback peer", got)
+	}
+}
+
+func TestWaitRefillsBucket(t *testing.T) {
+	l, err := New(Options{Default: Limit{RPS: 10, Burst: 2}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	method := pb.UserService_SearchUsers_FullMethodName
+	ctx := fromAddr(tcpAddr("192.0.2.1"))
+
+	for i := 0; i < 2; i++ {
+		if delay := l.Wait(ctx, method); delay != 0 {
+			t.Fatalf("call %d within the burst delayed by %v", i+1, delay)
+		}
+	}
+	delay := l.Wait(ctx, method)
+	if delay <= 0 || delay > 100*time.Millisecond {
+		t.Fatalf("call over the burst delayed by %v, want up to a token every 100ms", delay)
+	}
+	// A rejected call does not consume a token, so it does not push the next one back
+	if again := l.Wait(ctx, method); again <= 0 || again > delay {
+		t.Fatalf("retry delayed by %v after %v", again, delay)
+	}
+
+	time.Sleep(delay + 20*time.Millisecond)
+	if delay := l.Wait(ctx, method); delay != 0 {
+		t.Fatalf("call after the refill delayed by %v", delay)
+	}
+	if delay := l.Wait(ctx, method); delay == 0 {
+		t.Fatal("refill added more than one token")
+	}
+
+	// Buckets are per client and per method
+	if delay := l.Wait(fromAddr(tcpAddr("192.0.2.2")), method); delay != 0 {
+		t.Errorf("other client delayed by %v", delay)
+	}
+	if delay := l.Wait(ctx, pb.UserService_GetUserByID_FullMethodName); delay != 0 {
+		t.Errorf("other method delayed by %v", delay)
+	}
+}
+
+func TestWaitMethodLimits(t *testing.T) {
+	l, err := New(Options{
+		Default: Limit{RPS: 1, Burst: 1},
+		Methods: map[string]Limit{"GetUserByID": {RPS: 0}, "SearchUsers": {RPS: 1, Burst: 3}},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	ctx := fromAddr(tcpAddr("192.0.2.1"))
+	calls := func(method string) int {
+		n := 0
+		for i := 0; i < 10 && l.Wait(ctx, method) == 0; i++ {
+			n++
+		}
+		return n
+	}
+	if n := calls(pb.UserService_CreateUser_FullMethodName); n != 1 {
+		t.Errorf("default limit allowed %d calls, want 1", n)
+	}
+	if n := calls(pb.UserService_SearchUsers_FullMethodName); n != 3 {
+		t.Errorf("SearchUsers allowed %d calls, want its burst of 3", n)
+	}
+	if n := calls(pb.UserService_GetUserByID_FullMethodName); n != 10 {
+		t.Errorf("unlimited GetUserByID allowed %d calls", n)
+	}
+	if n := calls("/grpc.health.v1.Health/Check"); n != 10 {
+		t.Errorf("health checks allowed %d calls, want no limit", n)
+	}
+
+	if _, err := New(Options{Methods: map[string]Limit{"DropUsers": {RPS: 1}}}); err == nil {
+		t.Error("limit of an unknown method accepted")
+	}
+	if _, err := New(Options{TrustedProxies: []string{"10.0.0.0/33"}}); err == nil {
+		t.Error("invalid trusted proxy accepted")
+	}
+}
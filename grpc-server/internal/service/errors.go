@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/database"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// userResourceType is reported in ResourceInfo details for user errors
+const userResourceType = "users.User"
+
+// toStatus converts a store error into a gRPC status error. resourceName
+// identifies the user the request was about, e.g. "users/42", and may be empty.
+func toStatus(err error, resourceName string) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var invalid *database.InvalidArgumentError
+	switch {
+	case errors.As(err, &invalid):
+		badRequest := &errdetails.BadRequest{}
+		for _, v := range invalid.Violations {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       v.Field,
+				Description: v.Description,
+			})
+		}
+		return withDetails(codes.InvalidArgument, err.Error(), badRequest)
+	case errors.Is(err, database.ErrUserNotFound), errors.Is(err, database.ErrNoUsersFound):
+		return withDetails(codes.NotFound, err.Error(), resourceInfo(resourceName, err))
+	case errors.Is(err, database.ErrUserExists):
+		return withDetails(codes.AlreadyExists, err.Error(), resourceInfo(resourceName, err))
+	case errors.Is(err, database.ErrStoreUnavailable):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// resourceInfo describes the user resource an error refers to
+func resourceInfo(resourceName string, err error) *errdetails.ResourceInfo {
+	return &errdetails.ResourceInfo{
+		ResourceType: userResourceType,
+		ResourceName: resourceName,
+		Description:  err.Error(),
+	}
+}
+
+// withDetails builds a status error carrying detail, falling back to the bare
+// status if the detail cannot be attached
+func withDetails(code codes.Code, msg string, detail protoadapt.MessageV1) error {
+	st, err := status.New(code, msg).WithDetails(detail)
+	if err != nil {
+		return status.Error(code, msg)
+	}
+	return st.Err()
+}
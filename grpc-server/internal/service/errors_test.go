@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/database"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+func TestToStatus(t *testing.T) {
+	invalid := &database.InvalidArgumentError{Violations: []database.FieldViolation{
+		{Field: "criterias[0].field_name", Description: `unknown field "age"`},
+		{Field: "page_size", Description: "must not be negative"},
+	}}
+	tests := []struct {
+		name    string
+		err     error
+		code    codes.Code
+		details []protoadapt.MessageV1
+	}{
+		{"invalid argument", fmt.Errorf("searching: %w", invalid), codes.InvalidArgument, []protoadapt.MessageV1{
+			&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: "criterias[0].field_name", Description: `unknown field "age"`},
+				{Field: "page_size", Description: "must not be negative"},
+			}},
+		}},
+		{"user not found", fmt.Errorf("reading user 42: %w", database.ErrUserNotFound), codes.NotFound, []protoadapt.MessageV1{
+			&errdetails.ResourceInfo{ResourceType: userResourceType, ResourceName: "users/42", Description: "reading user 42: user not found"},
+		}},
+		{"no users found", database.ErrNoUsersFound, codes.NotFound, []protoadapt.MessageV1{
+			&errdetails.ResourceInfo{ResourceType: userResourceType, ResourceName: "users/42", Description: "no users found"},
+		}},
+		{"user exists", database.ErrUserExists, codes.AlreadyExists, []protoadapt.MessageV1{
+			&errdetails.ResourceInfo{ResourceType: userResourceType, ResourceName: "users/42", Description: "user already exists"},
+		}},
+		{"store unavailable", fmt.Errorf("bolt: %w", database.ErrStoreUnavailable), codes.Unavailable, nil},
+		{"canceled", fmt.Errorf("scanning: %w", context.Canceled), codes.Canceled, nil},
+		{"deadline exceeded", context.DeadlineExceeded, codes.DeadlineExceeded, nil},
+		{"unexpected error", errors.New("disk on fire"), codes.Internal, nil},
+		{"status error", status.Error(codes.PermissionDenied, "role reader may not call CreateUser"), codes.PermissionDenied, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, ok := status.FromError(toStatus(tt.err, "users/42"))
+			if !ok {
+				t.Fatalf("toStatus returned a non-status error")
+			}
+			if st.Code() != tt.code {
+				t.Errorf("code = %v, want %v", st.Code(), tt.code)
+			}
+			// The message of err, or of the status it already is
+			message := status.Convert(tt.err).Message()
+			if st.Message() != message {
+				t.Errorf("message = %q, want %q", st.Message(), message)
+			}
+			details := st.Details()
+			if len(details) != len(tt.details) {
+				t.Fatalf("details = %v, want %v", details, tt.details)
+			}
+			for i, detail := range details {
+				msg, ok := detail.(protoadapt.MessageV1)
+				if !ok {
+					t.Fatalf("detail %d could not be decoded: %v", i, detail)
+				}
+				if !proto.Equal(protoadapt.MessageV2Of(msg), protoadapt.MessageV2Of(tt.details[i])) {
+					t.Errorf("detail %d = %v, want %v", i, msg, tt.details[i])
+				}
+			}
+		})
+	}
+}
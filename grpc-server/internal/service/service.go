@@ -2,56 +2,160 @@ package service
 
 import (
 	"context"
+	"fmt"
 
 	pb "github.com/ParasJain0307/grpc-project/grpc-server/api"
 	"github.com/ParasJain0307/grpc-project/grpc-server/internal/database"
 	"github.com/ParasJain0307/grpc-project/grpc-server/internal/logger"
+	"google.golang.org/protobuf/types/known/emptypb"
 )
 
 // UserService implements the UserServiceServer interface
 type UserService struct {
 	pb.UnimplementedUserServiceServer
-	Database database.Database
+	Store database.UserStore
 }
 
-// NewService creates a new UserService instance
-func NewService() *UserService {
-	return &UserService{}
+// NewService creates a new UserService instance backed by store
+func NewService(store database.UserStore) *UserService {
+	return &UserService{Store: store}
 }
 
 // GetUserByID implements the GetUserByID method from the protobuf definition
 func (s *UserService) GetUserByID(ctx context.Context, req *pb.GetUserByIDRequest) (*pb.User, error) {
-	logger.Info("GetUserByID called user_id ", req.UserId)
-	user, err := s.Database.GetUserByID(req.UserId)
+	log := logger.FromContext(ctx)
+	log.Infow("GetUserByID called", "user_id", req.UserId)
+	user, err := s.Store.GetUserByID(ctx, req.UserId)
 	if err != nil {
-		logger.Error("Failed to get user by ID user_id ", req.UserId, "error ", err)
-		return nil, err
+		log.Errorw("Failed to get user by ID", "user_id", req.UserId, "error", err)
+		return nil, toStatus(err, userResourceName(req.UserId))
 	}
-	logger.Info("User retrieved by ID user_id ", req.UserId)
+	log.Infow("User retrieved by ID", "user_id", req.UserId)
 	return user, nil
 }
 
 // GetUsersByID implements the GetUsersByID method from the protobuf definition
 func (s *UserService) GetUsersByID(ctx context.Context, req *pb.GetUsersByIDRequest) (*pb.UsersList, error) {
-	logger.Info("GetUsersByID called user_ids ", req.UserIds)
-	users, err := s.Database.GetUsersByID(req.UserIds)
+	log := logger.FromContext(ctx)
+	log.Infow("GetUsersByID called", "user_ids", req.UserIds)
+	users, err := s.Store.GetUsersByID(ctx, req.UserIds)
 	if err != nil {
-		logger.Error("Failed to get users by IDs user_ids ", req.UserIds, "error ", err)
-		return nil, err
+		log.Errorw("Failed to get users by IDs", "user_ids", req.UserIds, "error", err)
+		return nil, toStatus(err, "")
 	}
-	logger.Info("Users retrieved by IDs num_users ", len(users))
-	return &pb.UsersList{Users: users}, nil
+	page, err := database.Paginate(users, database.PageRequest{
+		PageSize:  req.PageSize,
+		PageToken: req.PageToken,
+		OrderBy:   req.OrderBy,
+		Scope:     database.PageScope(&pb.GetUsersByIDRequest{UserIds: req.UserIds, OrderBy: req.OrderBy}),
+	})
+	if err != nil {
+		log.Errorw("Failed to paginate users by IDs", "user_ids", req.UserIds, "error", err)
+		return nil, toStatus(err, "")
+	}
+	log.Infow("Users retrieved by IDs", "num_users", len(page.Users), "total_size", page.TotalSize)
+	return usersList(page), nil
 }
 
 // SearchUsers implements the SearchUsers method from the protobuf definition
 func (s *UserService) SearchUsers(ctx context.Context, req *pb.SearchUsersRequest) (*pb.UsersList, error) {
-	logger.Info("SearchUsers called request ", req)
-	criteria := req.GetCriterias()
-	users, err := s.Database.SearchUsers(criteria)
+	log := logger.FromContext(ctx)
+	log.Infow("SearchUsers called", "request", req)
+	query := database.Query{
+		Criteria: req.GetCriterias(),
+		Filter:   req.GetFilter(),
+	}
+	users, err := s.Store.SearchUsers(ctx, query)
+	if err != nil {
+		log.Errorw("Failed to search users", "request", req, "error", err)
+		return nil, toStatus(err, "")
+	}
+	page, err := database.Paginate(users, database.PageRequest{
+		PageSize:  req.PageSize,
+		PageToken: req.PageToken,
+		OrderBy:   req.OrderBy,
+		Scope:     database.PageScope(&pb.SearchUsersRequest{Criterias: req.Criterias, Filter: req.Filter, OrderBy: req.OrderBy}),
+	})
+	if err != nil {
+		log.Errorw("Failed to paginate users", "request", req, "error", err)
+		return nil, toStatus(err, "")
+	}
+	log.Infow("Users found matching criteria", "num_users", len(page.Users), "total_size", page.TotalSize)
+	return usersList(page), nil
+}
+
+// StreamSearchUsers implements the StreamSearchUsers method from the protobuf definition
+func (s *UserService) StreamSearchUsers(req *pb.SearchUsersRequest, stream pb.UserService_StreamSearchUsersServer) error {
+	log := logger.FromContext(stream.Context())
+	log.Infow("StreamSearchUsers called", "request", req)
+	query := database.Query{
+		Criteria: req.GetCriterias(),
+		Filter:   req.GetFilter(),
+	}
+	sent := 0
+	err := s.Store.ScanUsers(stream.Context(), query, func(user *pb.User) error {
+		if err := stream.Send(user); err != nil {
+			return err
+		}
+		sent++
+		return nil
+	})
+	if err != nil {
+		log.Errorw("Failed to stream users", "request", req, "sent", sent, "error", err)
+		return toStatus(err, "")
+	}
+	log.Infow("Users streamed matching criteria", "num_users", sent)
+	return nil
+}
+
+// CreateUser implements the CreateUser method from the protobuf definition
+func (s *UserService) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.User, error) {
+	log := logger.FromContext(ctx)
+	log.Infow("CreateUser called", "user_id", req.GetUser().GetId())
+	user, err := s.Store.CreateUser(ctx, req.GetUser())
 	if err != nil {
-		logger.Error("Failed to search users request ", req, "error ", err)
-		return nil, err
+		log.Errorw("Failed to create user", "user_id", req.GetUser().GetId(), "error", err)
+		return nil, toStatus(err, userResourceName(req.GetUser().GetId()))
+	}
+	log.Infow("User created", "user_id", user.Id)
+	return user, nil
+}
+
+// UpdateUser implements the UpdateUser method from the protobuf definition
+func (s *UserService) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.User, error) {
+	log := logger.FromContext(ctx)
+	log.Infow("UpdateUser called", "user_id", req.GetUser().GetId(), "update_mask", req.GetUpdateMask().GetPaths())
+	user, err := s.Store.UpdateUser(ctx, req.GetUser(), req.GetUpdateMask().GetPaths())
+	if err != nil {
+		log.Errorw("Failed to update user", "user_id", req.GetUser().GetId(), "error", err)
+		return nil, toStatus(err, userResourceName(req.GetUser().GetId()))
+	}
+	log.Infow("User updated", "user_id", user.Id)
+	return user, nil
+}
+
+// DeleteUser implements the DeleteUser method from the protobuf definition
+func (s *UserService) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest) (*emptypb.Empty, error) {
+	log := logger.FromContext(ctx)
+	log.Infow("DeleteUser called", "user_id", req.UserId)
+	if err := s.Store.DeleteUser(ctx, req.UserId); err != nil {
+		log.Errorw("Failed to delete user", "user_id", req.UserId, "error", err)
+		return nil, toStatus(err, userResourceName(req.UserId))
+	}
+	log.Infow("User deleted", "user_id", req.UserId)
+	return &emptypb.Empty{}, nil
+}
+
+// userResourceName names a user in error details
+func userResourceName(id int32) string {
+	return fmt.Sprintf("users/%d", id)
+}
+
+// usersList converts a page of users into the protobuf response
+func usersList(page *database.Page) *pb.UsersList {
+	return &pb.UsersList{
+		Users:         page.Users,
+		NextPageToken: page.NextPageToken,
+		TotalSize:     page.TotalSize,
 	}
-	logger.Info("Users found matching criteria num_users ", len(users))
-	return &pb.UsersList{Users: users}, nil
 }
@@ -0,0 +1,267 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ParasJain0307/grpc-project/grpc-server/internal/logger"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	// Reloads are logged through the global logger, which main initializes
+	logger.SetLogger(zap.NewNop().Sugar())
+	os.Exit(m.Run())
+}
+
+// issued holds the PEM data of a CA, a server certificate and a client
+// certificate, both issued by the CA
+type issued struct {
+	ca, serverCert, serverKey, clientCert, clientKey []byte
+}
+
+// issue creates a CA named name and the certificates it issues, that of the
+// server being valid for hosts, localhost when there are none
+func issue(t *testing.T, name string, hosts ...string) issued {
+	t.Helper()
+	if len(hosts) == 0 {
+		hosts = []string{"localhost"}
+	}
+	ca, err := NewCA(name, time.Hour)
+	if err != nil {
+		t.Fatalf("NewCA: %v", err)
+	}
+	serverCert, serverKey, err := ca.IssueServer(hosts, time.Hour)
+	if err != nil {
+		t.Fatalf("IssueServer: %v", err)
+	}
+	clientCert, clientKey, err := ca.IssueClient("client", time.Hour)
+	if err != nil {
+		t.Fatalf("IssueClient: %v", err)
+	}
+	return issued{ca: ca.CertPEM, serverCert: serverCert, serverKey: serverKey, clientCert: clientCert, clientKey: clientKey}
+}
+
+// pki names the files issued data is written to
+type pki struct {
+	ca, serverCert, serverKey, clientCert, clientKey string
+}
+
+// write stores the files under dir, replacing those already there as a
+// rotation would
+func (i issued) write(t *testing.T, dir string) pki {
+	t.Helper()
+	p := pki{
+		ca:         filepath.Join(dir, "ca.pem"),
+		serverCert: filepath.Join(dir, "server.pem"),
+		serverKey:  filepath.Join(dir, "server-key.pem"),
+		clientCert: filepath.Join(dir, "client.pem"),
+		clientKey:  filepath.Join(dir, "client-key.pem"),
+	}
+	// Rotated files may have the same size, so move their modification time
+	// past that of the files they replace
+	modTime := time.Now()
+	if info, err := os.Stat(p.ca); err == nil {
+		modTime = info.ModTime().Add(time.Minute)
+	}
+	for path, data := range map[string][]byte{
+		p.ca: i.ca, p.serverCert: i.serverCert, p.serverKey: i.serverKey,
+		p.clientCert: i.clientCert, p.clientKey: i.clientKey,
+	} {
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return p
+}
+
+// handshake connects a client and a server over an in-memory connection and
+// returns the error each side saw. A rejected client certificate may only show
+// on the client when it first reads, so the client reads what the server writes.
+func handshake(t *testing.T, server, client *tls.Config) (serverErr, clientErr error) {
+	t.Helper()
+	serverConn, clientConn := net.Pipe()
+	deadline := time.Now().Add(5 * time.Second)
+	serverConn.SetDeadline(deadline)
+	clientConn.SetDeadline(deadline)
+
+	done := make(chan error, 1)
+	go func() {
+		conn := tls.Server(serverConn, server)
+		defer conn.Close()
+		err := conn.Handshake()
+		if err == nil {
+			_, err = conn.Write([]byte("ok"))
+		}
+		done <- err
+	}()
+
+	conn := tls.Client(clientConn, client)
+	clientErr = conn.Handshake()
+	if clientErr == nil {
+		buf := make([]byte, 2)
+		_, clientErr = conn.Read(buf)
+	}
+	// Closing the pipe rather than conn, whose close_notify nobody would read
+	clientConn.Close()
+	return <-done, clientErr
+}
+
+// address is the server dialed by the clients of the tests
+const address = "localhost:50051"
+
+func TestHandshakeTLS(t *testing.T) {
+	p := issue(t, "test CA").write(t, t.TempDir())
+	server, err := NewServerConfig(ServerOptions{CertFile: p.serverCert, KeyFile: p.serverKey})
+	if err != nil {
+		t.Fatalf("NewServerConfig: %v", err)
+	}
+	client, err := NewClientConfig(address, ClientOptions{CAFile: p.ca, ServerName: "localhost"})
+	if err != nil {
+		t.Fatalf("NewClientConfig: %v", err)
+	}
+	if serverErr, clientErr := handshake(t, server, client); serverErr != nil || clientErr != nil {
+		t.Fatalf("handshake failed: server %v, client %v", serverErr, clientErr)
+	}
+
+	// The server certificate is only valid for localhost
+	other, err := NewClientConfig(address, ClientOptions{CAFile: p.ca, ServerName: "example.com"})
+	if err != nil {
+		t.Fatalf("NewClientConfig: %v", err)
+	}
+	if _, clientErr := handshake(t, server, other); clientErr == nil {
+		t.Error("client accepted a certificate for another host")
+	}
+}
+
+func TestHandshakeMutualTLS(t *testing.T) {
+	p := issue(t, "test CA").write(t, t.TempDir())
+	untrusted := issue(t, "untrusted CA").write(t, t.TempDir())
+	server, err := NewServerConfig(ServerOptions{CertFile: p.serverCert, KeyFile: p.serverKey, ClientCAFile: p.ca})
+	if err != nil {
+		t.Fatalf("NewServerConfig: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		opts   ClientOptions
+		reject bool
+	}{
+		{"trusted certificate", ClientOptions{CertFile: p.clientCert, KeyFile: p.clientKey}, false},
+		{"no certificate", ClientOptions{}, true},
+		{"certificate of an untrusted CA", ClientOptions{CertFile: untrusted.clientCert, KeyFile: untrusted.clientKey}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.CAFile, tt.opts.ServerName = p.ca, "localhost"
+			client, err := NewClientConfig(address, tt.opts)
+			if err != nil {
+				t.Fatalf("NewClientConfig: %v", err)
+			}
+			serverErr, clientErr := handshake(t, server, client)
+			if !tt.reject {
+				if serverErr != nil || clientErr != nil {
+					t.Fatalf("handshake failed: server %v, client %v", serverErr, clientErr)
+				}
+				return
+			}
+			if serverErr == nil {
+				t.Error("server accepted the client")
+			}
+			if clientErr == nil {
+				t.Error("client was not told it was rejected")
+			}
+		})
+	}
+}
+
+func TestHandshakeAfterRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldCA := issue(t, "old CA")
+	old := oldCA.write(t, dir)
+	// A copy of the old files kept by clients that are not rotated
+	kept := oldCA.write(t, t.TempDir())
+
+	server, err := NewServerConfig(ServerOptions{CertFile: old.serverCert, KeyFile: old.serverKey, ClientCAFile: old.ca})
+	if err != nil {
+		t.Fatalf("NewServerConfig: %v", err)
+	}
+	clientOpts := ClientOptions{CAFile: old.ca, CertFile: old.clientCert, KeyFile: old.clientKey, ServerName: "localhost"}
+	client, err := NewClientConfig(address, clientOpts)
+	if err != nil {
+		t.Fatalf("NewClientConfig: %v", err)
+	}
+	if serverErr, clientErr := handshake(t, server, client); serverErr != nil || clientErr != nil {
+		t.Fatalf("handshake before rotation failed: server %v, client %v", serverErr, clientErr)
+	}
+
+	// Every file of both sides is replaced by one of a new CA; the configs
+	// built before must pick them up on the next handshake
+	rotated := issue(t, "new CA").write(t, dir)
+	if serverErr, clientErr := handshake(t, server, client); serverErr != nil || clientErr != nil {
+		t.Fatalf("handshake after rotation failed: server %v, client %v", serverErr, clientErr)
+	}
+
+	// A client still trusting the old CA rejects the new server certificate
+	stale, err := NewClientConfig(address, ClientOptions{CAFile: kept.ca, ServerName: "localhost"})
+	if err != nil {
+		t.Fatalf("NewClientConfig: %v", err)
+	}
+	if _, clientErr := handshake(t, server, stale); clientErr == nil {
+		t.Error("client trusting the old CA accepted the rotated server certificate")
+	}
+	// and the server no longer trusts client certificates of the old CA
+	staleCert, err := NewClientConfig(address, ClientOptions{CAFile: rotated.ca, CertFile: kept.clientCert, KeyFile: kept.clientKey, ServerName: "localhost"})
+	if err != nil {
+		t.Fatalf("NewClientConfig: %v", err)
+	}
+	if serverErr, _ := handshake(t, server, staleCert); serverErr == nil {
+		t.Error("server accepted a client certificate of the old CA after rotation")
+	}
+}
+
+func TestHandshakeVerifiesDialedHost(t *testing.T) {
+	tests := []struct {
+		name       string
+		hosts      []string // SANs of the server certificate
+		address    string
+		serverName string
+		reject     bool
+	}{
+		{"IP address in the IP SANs", []string{"10.1.2.3"}, "10.1.2.3:50051", "", false},
+		{"IPv6 address in the IP SANs", []string{"::1"}, "[::1]:50051", "", false},
+		{"host name in the DNS SANs", []string{"example.com"}, "example.com:50051", "", false},
+		{"server name overriding the dialed IP", []string{"example.com"}, "10.1.2.3:50051", "example.com", false},
+		{"IP address with a certificate for another name", []string{"example.com"}, "10.1.2.3:50051", "", true},
+		{"IP address not in the IP SANs", []string{"10.1.2.3"}, "10.9.9.9:50051", "", true},
+		{"host name with a certificate for another name", []string{"example.com"}, "other.example.com:50051", "", true},
+		{"no name to check", []string{"example.com"}, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := issue(t, "test CA", tt.hosts...).write(t, t.TempDir())
+			server, err := NewServerConfig(ServerOptions{CertFile: p.serverCert, KeyFile: p.serverKey})
+			if err != nil {
+				t.Fatalf("NewServerConfig: %v", err)
+			}
+			client, err := NewClientConfig(tt.address, ClientOptions{CAFile: p.ca, ServerName: tt.serverName})
+			if err != nil {
+				t.Fatalf("NewClientConfig: %v", err)
+			}
+			_, clientErr := handshake(t, server, client)
+			if tt.reject && clientErr == nil {
+				t.Error("client accepted the certificate")
+			}
+			if !tt.reject && clientErr != nil {
+				t.Errorf("handshake failed: %v", clientErr)
+			}
+		})
+	}
+}
@@ -1,13 +1,9 @@
 package utils
 
 const (
-	FIRSTNAME      = "fname"
-	CITY           = "city"
-	PHONE          = "phone"
-	HEIGHT         = "height"
-	MARRIED        = "married"
-	GRPCSERVERPORT = ":50051"
-	HTTPSERVERPORT = ":8082"
-	GRPCSERVERADDR = "localhost"
-	HTTPSERVERADDR = "localhost"
+	FIRSTNAME = "fname"
+	CITY      = "city"
+	PHONE     = "phone"
+	HEIGHT    = "height"
+	MARRIED   = "married"
 )
@@ -0,0 +1,54 @@
+// Package tlsverify checks the certificate of a server against a CA bundle
+// that may change between handshakes, for the clients of the service and the
+// gateway dialing the gRPC server.
+package tlsverify
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"strings"
+)
+
+// ServerName returns the name the certificate of a server dialed at address
+// must be valid for: name when set, else the host of address
+func ServerName(name, address string) string {
+	if name != "" {
+		return name
+	}
+	// gRPC targets may carry a scheme, as in dns:///host:port
+	if i := strings.LastIndex(address, "/"); i >= 0 {
+		address = address[i+1:]
+	}
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return host
+	}
+	return address
+}
+
+// Peer checks the certificate chain the server presented against roots and
+// that the leaf certificate is valid for serverName. An IP address is matched
+// against the IP SANs of the certificate, any other name against its DNS SANs.
+//
+// serverName is the name that was dialed rather than the SNI of the handshake,
+// which is empty for IP addresses and would skip the check of the name.
+func Peer(state tls.ConnectionState, roots *x509.CertPool, serverName string) error {
+	if serverName == "" {
+		return errors.New("tls: no server name to verify the certificate against")
+	}
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("tls: server presented no certificate")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	// Verify matches IP SANs when DNSName is an IP address
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		DNSName:       strings.TrimSuffix(strings.TrimPrefix(serverName, "["), "]"),
+	})
+	return err
+}
@@ -0,0 +1,33 @@
+package tlsverify
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+)
+
+func TestServerName(t *testing.T) {
+	tests := []struct {
+		name, address, want string
+	}{
+		{"example.com", "10.1.2.3:50051", "example.com"},
+		{"", "10.1.2.3:50051", "10.1.2.3"},
+		{"", "[::1]:50051", "::1"},
+		{"", "users.internal:50051", "users.internal"},
+		{"", "dns:///users.internal:50051", "users.internal"},
+		{"", "users.internal", "users.internal"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		if got := ServerName(tt.name, tt.address); got != tt.want {
+			t.Errorf("ServerName(%q, %q) = %q, want %q", tt.name, tt.address, got, tt.want)
+		}
+	}
+}
+
+func TestPeerRequiresServerName(t *testing.T) {
+	state := tls.ConnectionState{PeerCertificates: []*x509.Certificate{{}}}
+	if err := Peer(state, x509.NewCertPool(), ""); err == nil {
+		t.Error("certificate accepted without a server name to check")
+	}
+}